@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/types"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxBatchSize - ограничение размера пакетного запроса, если MaxBatchSize не задан.
+const defaultMaxBatchSize = 100
+
+// CreatePeopleBatch создает сразу несколько человек из JSON-массива.
+// Каждое уникальное имя обогащается один раз пакетными запросами к API,
+// записи сохраняются одним INSERT. Если квота API исчерпана, записи сохраняются
+// необогащенными и ставятся в очередь. Результат возвращается для каждого элемента:
+// 201 - создан, 400 - некорректные данные, 502 - не удалось обогатить.
+func (h *PeopleHandler) CreatePeopleBatch(c *gin.Context) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body must be a JSON array of people"})
+		return
+	}
+
+	maxSize := h.MaxBatchSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxBatchSize
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch is empty"})
+		return
+	}
+	if len(items) > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Batch must contain at most %d people", maxSize)})
+		return
+	}
+
+	results := make([]types.BatchItemResult, len(items))
+	var people []*types.Person
+	var positions []int // позиции people в запросе
+	for i, raw := range items {
+		results[i].Index = i
+
+		person := &types.Person{}
+		if err := json.Unmarshal(raw, person); err != nil {
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Failed to decode person"
+			continue
+		}
+		if err := types.ValidatePerson(person); err != nil {
+			results[i].Status, results[i].Error = http.StatusBadRequest, err.Error()
+			continue
+		}
+
+		people = append(people, person)
+		positions = append(positions, i)
+	}
+
+	// Обогащаем все корректные записи; сохраняются записи, обогащенные хотя бы частично
+	var enriched []*types.Person
+	var enrichedPositions []int
+	for j, err := range enrich.RunBatch(c.Request.Context(), people, h.Enrichers) {
+		if enrich.ShouldDefer(err, people[j]) {
+			log.Printf("Error enriching person %q, queueing missing fields: %v", people[j].Name, err)
+			people[j].EnrichmentStatus = types.EnrichmentPending
+			err = nil
+		}
+		if err != nil {
+			log.Printf("Error enriching person %q: %v", people[j].Name, err)
+			results[positions[j]].Status, results[positions[j]].Error = http.StatusBadGateway, err.Error()
+			continue
+		}
+		enriched = append(enriched, people[j])
+		enrichedPositions = append(enrichedPositions, positions[j])
+	}
+
+	if err := h.Repository.CreatePeople(enriched, h.actor(c)); err != nil {
+		log.Printf("Error CreatePeople: %v", err)
+		for _, i := range enrichedPositions {
+			results[i].Status, results[i].Error = http.StatusInternalServerError, "Failed to create person"
+		}
+	} else {
+		for j, i := range enrichedPositions {
+			results[i].Status, results[i].Person = http.StatusCreated, enriched[j]
+		}
+	}
+
+	response := types.BatchResult{Items: results}
+	for _, result := range results {
+		if result.Status == http.StatusCreated {
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+
+	// Если создано не все, ответ содержит смешанные результаты
+	status := http.StatusCreated
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	db "junior-test/db/models"
+	"junior-test/pkg/cursor"
+	"junior-test/pkg/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listPeopleByCursor отдает страницу списка людей в режиме keyset-пагинации.
+func (h *PeopleHandler) listPeopleByCursor(c *gin.Context, filter types.PersonFilter) {
+	fingerprint := filterFingerprint(filter)
+
+	if *filter.Cursor != "" {
+		var position types.CursorPosition
+		if err := cursor.Decode(h.CursorSecret, *filter.Cursor, &position); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": gin.H{"cursor": "is invalid"}})
+			return
+		}
+		if position.Filter != fingerprint {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": gin.H{"cursor": "was issued for different filter parameters"}})
+			return
+		}
+		filter.After = &position
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	pageSize := filter.PageSize
+	filter.PageSize++
+
+	people, err := h.Repository.FilterListPeople(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve people"})
+		return
+	}
+
+	page := types.PersonCursorPage{
+		Items:    people,
+		PageSize: pageSize,
+	}
+	if page.Items == nil {
+		page.Items = []*types.Person{}
+	}
+
+	if len(people) > pageSize {
+		page.Items = people[:pageSize]
+		last := page.Items[pageSize-1]
+
+		order, _ := db.ParseSort(filter.Sort) // уже проверено при разборе фильтра
+		position := types.CursorPosition{Keys: db.SortKeys(order, last), ID: last.ID, Filter: fingerprint}
+
+		next, err := cursor.Encode(h.CursorSecret, position)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve people"})
+			return
+		}
+		page.NextCursor = &next
+		page.Next = cursorLink(c.Request.URL.Path, filter, next, pageSize)
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// filterFingerprint вычисляет отпечаток условий фильтра без параметров пагинации.
+// Курсор принимается только вместе с теми же условиями, для которых был выдан.
+func filterFingerprint(filter types.PersonFilter) string {
+	filter.Page, filter.PageSize = 0, 0
+	filter.Cursor, filter.After = nil, nil
+
+	data, _ := json.Marshal(filter)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// cursorLink возвращает ссылку на следующую страницу с условиями фильтра filter.
+func cursorLink(path string, filter types.PersonFilter, next string, pageSize int) *string {
+	query := filterQuery(filter)
+	query.Set("cursor", next)
+	query.Set("pagesize", strconv.Itoa(pageSize))
+
+	link := path + "?" + query.Encode()
+	return &link
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	db "junior-test/db/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isAdmin проверяет токен администратора в заголовке X-Admin-Token.
+func (h *PeopleHandler) isAdmin(c *gin.Context) bool {
+	token := c.GetHeader("X-Admin-Token")
+	return h.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) == 1
+}
+
+// RequireAdmin пропускает только запросы с токеном администратора.
+func (h *PeopleHandler) RequireAdmin(c *gin.Context) {
+	if !h.isAdmin(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+		return
+	}
+	c.Next()
+}
+
+// RestorePerson восстанавливает удаленную запись, пока она не удалена окончательно.
+func (h *PeopleHandler) RestorePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	_, err = h.Repository.RestorePerson(id, ifMatchVersions(c.Request.Header.Values("If-Match")), h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted person not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVersionMismatch) {
+		preconditionFailed(c)
+		return
+	}
+	if err != nil {
+		log.Printf("Error RestorePerson: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore person"})
+		return
+	}
+
+	person, err := h.Repository.GetPersonByID(id)
+	if err != nil || person == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+
+	setETag(c, person.Version)
+	c.JSON(http.StatusOK, person)
+}
+
+// PurgePerson окончательно удаляет ранее удаленную запись, не дожидаясь
+// окончания срока хранения. Доступен только администратору.
+func (h *PeopleHandler) PurgePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	err = h.Repository.PurgePerson(id, h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted person not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge person"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person purged successfully"})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	db "junior-test/db/models"
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/types"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnrichMissingFields повторно обогащает поля записи, которые не удалось обогатить
+// раньше; уже обогащенные поля не запрашиваются. Поля, которые снова не удалось
+// обогатить, сохраняют статус failed. Если не обогащено ни одно поле, запись не изменяется.
+func (h *PeopleHandler) EnrichMissingFields(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	person, err := h.Repository.GetPersonByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+	if person == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if !checkIfMatch(c, person.Version) {
+		return
+	}
+	if person.EnrichmentStatus == types.EnrichmentPending {
+		// Оставшиеся поля обогатит пул воркеров по заданию из очереди
+		c.JSON(http.StatusConflict, gin.H{"error": "Person enrichment is already queued"})
+		return
+	}
+	if person.EnrichedCount() == len(types.EnrichedFields) {
+		setETag(c, person.Version)
+		c.JSON(http.StatusOK, person)
+		return
+	}
+
+	enriched := person.EnrichedCount()
+	if err := enrich.Run(c.Request.Context(), person, h.Enrichers); err != nil {
+		log.Printf("Error enriching missing fields of person with ID %d: %v", id, err)
+	}
+	if person.EnrichedCount() == enriched {
+		var quotaErr *enrich.QuotaError
+		if errors.As(err, &quotaErr) {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(quotaErr.Reset).Seconds())+1))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enrichment quota exceeded"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to enrich person"})
+		return
+	}
+	person.MarkUnenriched(types.EnrichmentFailed)
+
+	// Запись не должна измениться, пока выполнялись запросы к источникам
+	_, err = h.Repository.SaveEnrichment(id, person, h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVersionMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Person has been modified during enrichment"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save enrichment"})
+		return
+	}
+
+	updated, err := h.Repository.GetPersonByID(id)
+	if err != nil || updated == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, updated)
+}
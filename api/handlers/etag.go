@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag передает версию записи в заголовке ETag.
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersions разбирает заголовки If-Match в список версий записи.
+// Возвращает nil, если заголовка нет или он равен "*" (подходит любая существующая запись).
+// Слабые (W/"...") и нераспознанные теги не совпадают ни с одной версией (RFC 7232),
+// поэтому при их наличии возвращается пустой, но не nil список.
+func ifMatchVersions(headers []string) []int {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	versions := []int{}
+	for _, header := range headers {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				return nil
+			}
+
+			unquoted, err := strconv.Unquote(tag)
+			if err != nil || !strings.HasPrefix(tag, `"`) {
+				continue
+			}
+			if version, err := strconv.Atoi(unquoted); err == nil {
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions
+}
+
+// checkIfMatch проверяет версию записи по заголовку If-Match и при несовпадении
+// отправляет 412 Precondition Failed.
+func checkIfMatch(c *gin.Context, version int) bool {
+	versions := ifMatchVersions(c.Request.Header.Values("If-Match"))
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	preconditionFailed(c)
+	return false
+}
+
+// preconditionFailed сообщает, что запись изменилась после получения клиентом ее ETag.
+func preconditionFailed(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Person has been modified, If-Match does not match the current ETag"})
+}
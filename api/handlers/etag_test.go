@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		headers []string
+		want    []int
+	}{
+		{nil, nil},
+		{[]string{"*"}, nil},
+		{[]string{`"3"`}, []int{3}},
+		{[]string{`"3", "5"`, `"7"`}, []int{3, 5, 7}},
+		{[]string{`W/"3"`}, []int{}},
+		{[]string{`3`, `"abc"`}, []int{}},
+	}
+
+	for _, tt := range tests {
+		got := ifMatchVersions(tt.headers)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("ifMatchVersions(%q) = %#v, expected %#v", tt.headers, got, tt.want)
+		}
+	}
+}
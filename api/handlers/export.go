@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"junior-test/pkg/export"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportPeople выгружает всех людей, подходящих под фильтр (те же параметры, что
+// у списка, без пагинации), в формате format: csv (по умолчанию), ndjson или xlsx.
+// Параметр columns задает столбцы через запятую. Записи читаются курсором и
+// отправляются клиенту по мере чтения.
+func (h *PeopleHandler) ExportPeople(c *gin.Context) {
+	filter, errs := parsePersonFilter(c.Request)
+	if errs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": errs})
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'format' must be one of csv, ndjson, xlsx"})
+		return
+	}
+
+	var names []string
+	for _, value := range c.QueryArray("columns") {
+		names = append(names, strings.Split(value, ",")...)
+	}
+	columns, err := export.ParseColumns(names)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid columns: " + err.Error()})
+		return
+	}
+
+	if filter.IncludeDeleted && !h.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Filter include_deleted is available only to administrators"})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="people.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	w, err := export.NewWriter(c.Writer, format, columns)
+	if err == nil {
+		err = h.Repository.ExportPeople(c.Request.Context(), filter, w.Write)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Printf("Error ExportPeople: %v", err)
+		// Пока ничего не отправлено, клиент получает ошибку; иначе выгрузка обрывается
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export people"})
+		}
+	}
+}
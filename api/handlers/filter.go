@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	db "junior-test/db/models"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// filterParam описывает параметр строки запроса, задающий поле PersonFilter.
+type filterParam struct {
+	name string
+	set  func(filter *types.PersonFilter, values []string) error
+}
+
+// filterParams - параметры, поддерживаемые при получении списка людей.
+// Отрицание записывается как "nationality!=RU" (параметр "nationality!").
+var filterParams = []filterParam{
+	{"name", stringParam(func(f *types.PersonFilter) *string { return &f.Name })},
+	{"surname", stringParam(func(f *types.PersonFilter) *string { return &f.Surname })},
+	{"patronymic", stringParam(func(f *types.PersonFilter) *string { return &f.Patronymic })},
+	{"minage", intPtrParam(func(f *types.PersonFilter) **int { return &f.MinAge })},
+	{"maxage", intPtrParam(func(f *types.PersonFilter) **int { return &f.MaxAge })},
+	{"gender", listParam(func(f *types.PersonFilter) *types.StringList { return &f.Gender })},
+	{"gender!", listParam(func(f *types.PersonFilter) *types.StringList { return &f.NotGender })},
+	{"nationality", listParam(func(f *types.PersonFilter) *types.StringList { return &f.Nationality })},
+	{"nationality!", listParam(func(f *types.PersonFilter) *types.StringList { return &f.NotNationality })},
+	{"null", listParam(func(f *types.PersonFilter) *types.StringList { return &f.IsNull })},
+	{"notnull", listParam(func(f *types.PersonFilter) *types.StringList { return &f.NotNull })},
+	{"page", intParam(func(f *types.PersonFilter) *int { return &f.Page })},
+	{"pagesize", intParam(func(f *types.PersonFilter) *int { return &f.PageSize })},
+	{"match", stringParam(func(f *types.PersonFilter) *string { return &f.Match })},
+	{"q", stringParam(func(f *types.PersonFilter) *string { return &f.Q })},
+	{"search", stringParam(func(f *types.PersonFilter) *string { return &f.Search })},
+	{"sort", stringParam(func(f *types.PersonFilter) *string { return &f.Sort })},
+	{"include_deleted", boolParam(func(f *types.PersonFilter) *bool { return &f.IncludeDeleted })},
+	{"cursor", func(f *types.PersonFilter, values []string) error {
+		if len(values) > 1 {
+			return errSpecifiedOnce
+		}
+		f.Cursor = &values[0]
+		return nil
+	}},
+}
+
+var (
+	errSpecifiedOnce = fmt.Errorf("must be specified only once")
+	errNotNatural    = fmt.Errorf("must be a non-negative integer")
+	errNotBool       = fmt.Errorf("must be true or false")
+)
+
+// stringParam задает строковое поле фильтра.
+func stringParam(field func(filter *types.PersonFilter) *string) func(*types.PersonFilter, []string) error {
+	return func(filter *types.PersonFilter, values []string) error {
+		if len(values) > 1 {
+			return errSpecifiedOnce
+		}
+		*field(filter) = values[0]
+		return nil
+	}
+}
+
+// intParam разбирает неотрицательное целое значение параметра.
+func intParam(field func(filter *types.PersonFilter) *int) func(*types.PersonFilter, []string) error {
+	return func(filter *types.PersonFilter, values []string) error {
+		if len(values) > 1 {
+			return errSpecifiedOnce
+		}
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 0 {
+			return errNotNatural
+		}
+		*field(filter) = n
+		return nil
+	}
+}
+
+// intPtrParam разбирает необязательное неотрицательное целое значение (ноль - тоже значение).
+func intPtrParam(field func(filter *types.PersonFilter) **int) func(*types.PersonFilter, []string) error {
+	return func(filter *types.PersonFilter, values []string) error {
+		var n int
+		if err := intParam(func(*types.PersonFilter) *int { return &n })(filter, values); err != nil {
+			return err
+		}
+		*field(filter) = &n
+		return nil
+	}
+}
+
+// boolParam разбирает логическое значение параметра (true/false, 1/0).
+func boolParam(field func(filter *types.PersonFilter) *bool) func(*types.PersonFilter, []string) error {
+	return func(filter *types.PersonFilter, values []string) error {
+		if len(values) > 1 {
+			return errSpecifiedOnce
+		}
+		b, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return errNotBool
+		}
+		*field(filter) = b
+		return nil
+	}
+}
+
+// listParam собирает список значений: параметр можно повторять
+// и перечислять значения через запятую (?gender=male&nationality=RU,UA).
+func listParam(field func(filter *types.PersonFilter) *types.StringList) func(*types.PersonFilter, []string) error {
+	return func(filter *types.PersonFilter, values []string) error {
+		var list types.StringList
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+		}
+		*field(filter) = list
+		return nil
+	}
+}
+
+// parsePersonFilter собирает фильтр из JSON-тела запроса (для совместимости)
+// и параметров строки запроса. Параметры строки запроса имеют приоритет.
+// Ошибки возвращаются по каждому параметру отдельно.
+func parsePersonFilter(r *http.Request) (types.PersonFilter, map[string]string) {
+	var filter types.PersonFilter
+	errs := map[string]string{}
+
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil && err != io.EOF {
+			errs["body"] = "must be a valid JSON filter"
+			return filter, errs
+		}
+	}
+
+	applyFilterQuery(&filter, r.URL.Query(), errs)
+	validatePersonFilter(&filter, errs)
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+	return filter, nil
+}
+
+// applyFilterQuery переносит известные параметры строки запроса в фильтр.
+func applyFilterQuery(filter *types.PersonFilter, query url.Values, errs map[string]string) {
+	for _, param := range filterParams {
+		values, ok := query[param.name]
+		if !ok {
+			continue
+		}
+		if err := param.set(filter, values); err != nil {
+			errs[param.name] = err.Error()
+		}
+	}
+}
+
+// filterQuery кодирует условия фильтра в параметры строки запроса, которые
+// parsePersonFilter разберет в тот же фильтр. Параметры пагинации не включаются.
+func filterQuery(filter types.PersonFilter) url.Values {
+	query := url.Values{}
+	values := map[string]string{
+		"name": filter.Name, "surname": filter.Surname, "patronymic": filter.Patronymic,
+		"match": filter.Match, "q": filter.Q, "search": filter.Search, "sort": filter.Sort,
+	}
+	for name, value := range values {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	for name, value := range map[string]*int{"minage": filter.MinAge, "maxage": filter.MaxAge} {
+		if value != nil {
+			query.Set(name, strconv.Itoa(*value))
+		}
+	}
+	lists := map[string]types.StringList{
+		"gender": filter.Gender, "gender!": filter.NotGender, "nationality": filter.Nationality,
+		"nationality!": filter.NotNationality, "null": filter.IsNull, "notnull": filter.NotNull,
+	}
+	for name, list := range lists {
+		if len(list) > 0 {
+			query[name] = append([]string(nil), list...)
+		}
+	}
+	if filter.IncludeDeleted {
+		query.Set("include_deleted", "true")
+	}
+	return query
+}
+
+// validatePersonFilter проверяет согласованность значений фильтра.
+func validatePersonFilter(filter *types.PersonFilter, errs map[string]string) {
+	if filter.MinAge != nil && *filter.MinAge < 0 {
+		errs["minage"] = errNotNatural.Error()
+	}
+	if filter.MaxAge != nil && *filter.MaxAge < 0 {
+		errs["maxage"] = errNotNatural.Error()
+	}
+	if filter.MinAge != nil && filter.MaxAge != nil && *filter.MinAge > *filter.MaxAge {
+		errs["maxage"] = "must be greater than or equal to minage"
+	}
+	for param, fields := range map[string]types.StringList{"null": filter.IsNull, "notnull": filter.NotNull} {
+		for _, field := range fields {
+			if !db.ValidNullField(field) {
+				errs[param] = fmt.Sprintf("unknown field %q, expected patronymic, age, gender or nationality", field)
+			}
+		}
+	}
+	if filter.Page < 0 {
+		errs["page"] = errNotNatural.Error()
+	}
+	if filter.PageSize < 0 {
+		errs["pagesize"] = errNotNatural.Error()
+	}
+	if !db.ValidMatch(filter.Match) {
+		errs["match"] = "must be one of exact, prefix, contains"
+	}
+	if !db.ValidSearch(filter.Search) {
+		errs["search"] = "must be one of fulltext, fuzzy"
+	}
+	if filter.Search == db.SearchFuzzy && filter.Q == "" {
+		errs["q"] = "is required for fuzzy search"
+	}
+	if filter.Q != "" && db.SearchQuery(filter.Q) == "" {
+		errs["q"] = "must contain letters or digits"
+	}
+	if _, err := db.ParseSort(filter.Sort); err != nil {
+		errs["sort"] = err.Error()
+	}
+	if filter.Cursor != nil && db.RelevanceOrder(*filter) {
+		// Порядок по релевантности не входит в курсор, поэтому его нельзя молча заменить другим
+		errs["cursor"] = "cannot be combined with q without sort: results are ordered by relevance"
+	}
+}
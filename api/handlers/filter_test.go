@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePersonFilterQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/people?name=Oleg&minage=20&maxage=29&page=2&pagesize=10", nil)
+
+	filter, errs := parsePersonFilter(r)
+	if errs != nil {
+		t.Fatalf("Expected no errors, but got %v", errs)
+	}
+
+	if filter.Name != "Oleg" || *filter.MinAge != 20 || *filter.MaxAge != 29 || filter.Page != 2 || filter.PageSize != 10 {
+		t.Fatalf("Unexpected filter: %+v", filter)
+	}
+}
+
+func TestParsePersonFilterBody(t *testing.T) {
+	r := httptest.NewRequest("GET", "/filter?gender=Female", strings.NewReader(`{"gender": "Male", "minage": 18}`))
+
+	filter, errs := parsePersonFilter(r)
+	if errs != nil {
+		t.Fatalf("Expected no errors, but got %v", errs)
+	}
+
+	// Параметры строки запроса имеют приоритет над телом
+	if len(filter.Gender) != 1 || filter.Gender[0] != "Female" || *filter.MinAge != 18 {
+		t.Fatalf("Unexpected filter: %+v", filter)
+	}
+}
+
+func TestParsePersonFilterLists(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/people?gender=male&nationality=RU,UA&nationality=BY&nationality!=KZ&null=age&minage=0&include_deleted=true", nil)
+
+	filter, errs := parsePersonFilter(r)
+	if errs != nil {
+		t.Fatalf("Expected no errors, but got %v", errs)
+	}
+
+	if len(filter.Gender) != 1 || len(filter.Nationality) != 3 || filter.Nationality[2] != "BY" {
+		t.Fatalf("Unexpected lists: %+v", filter)
+	}
+	if len(filter.NotNationality) != 1 || filter.NotNationality[0] != "KZ" {
+		t.Fatalf("Unexpected negation: %+v", filter.NotNationality)
+	}
+	if len(filter.IsNull) != 1 || filter.IsNull[0] != "age" {
+		t.Fatalf("Unexpected null filter: %+v", filter.IsNull)
+	}
+	if filter.MinAge == nil || *filter.MinAge != 0 || filter.MaxAge != nil {
+		t.Fatalf("Zero minage must be kept, unset maxage must be nil: %+v", filter)
+	}
+	if !filter.IncludeDeleted {
+		t.Fatalf("Expected include_deleted to be set")
+	}
+
+	r = httptest.NewRequest("GET", "/filter", strings.NewReader(`{"gender": ["male", "female"], "nationality": "RU"}`))
+	if filter, errs = parsePersonFilter(r); errs != nil || len(filter.Gender) != 2 || len(filter.Nationality) != 1 {
+		t.Fatalf("Unexpected JSON lists: %+v, %v", filter, errs)
+	}
+
+	r = httptest.NewRequest("GET", "/api/v1/people?null=name", nil)
+	if _, errs = parsePersonFilter(r); errs["null"] == "" {
+		t.Fatalf("Expected error for unsupported null field, got %v", errs)
+	}
+}
+
+func TestParsePersonFilterErrors(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/people?minage=abc&page=-1&pagesize=1&pagesize=2&include_deleted=yes", nil)
+
+	_, errs := parsePersonFilter(r)
+	for _, param := range []string{"minage", "page", "pagesize", "include_deleted"} {
+		if _, ok := errs[param]; !ok {
+			t.Fatalf("Expected error for %q, got %v", param, errs)
+		}
+	}
+
+	r = httptest.NewRequest("GET", "/api/v1/people?minage=30&maxage=20", nil)
+	if _, errs = parsePersonFilter(r); errs["maxage"] == "" {
+		t.Fatalf("Expected error for maxage < minage, got %v", errs)
+	}
+
+	r = httptest.NewRequest("GET", "/api/v1/people?q=dmit&cursor=", nil)
+	if _, errs = parsePersonFilter(r); errs["cursor"] == "" {
+		t.Fatalf("Expected error for cursor with relevance order, got %v", errs)
+	}
+	r = httptest.NewRequest("GET", "/api/v1/people?q=dmit&sort=surname&cursor=", nil)
+	if _, errs = parsePersonFilter(r); errs != nil {
+		t.Fatalf("Expected cursor with explicit sort to be accepted, got %v", errs)
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	// Условия из тела запроса должны попасть в ссылки на соседние страницы
+	r := httptest.NewRequest("GET", "/filter?page=2", strings.NewReader(`{"surname": "Samsonov", "gender": ["male", "female"], "not_nationality": "RU", "minage": 0, "null": "patronymic"}`))
+	filter, errs := parsePersonFilter(r)
+	if errs != nil {
+		t.Fatalf("Expected no errors, but got %v", errs)
+	}
+
+	link := *pageLink("/filter", filterQuery(filter), 3, 10)
+	parsed, errs := parsePersonFilter(httptest.NewRequest("GET", link, nil))
+	if errs != nil {
+		t.Fatalf("Expected no errors for %s, but got %v", link, errs)
+	}
+	if parsed.Surname != "Samsonov" || len(parsed.Gender) != 2 || len(parsed.NotNationality) != 1 || parsed.MinAge == nil || *parsed.MinAge != 0 {
+		t.Fatalf("Filter was not kept in %s: %+v", link, parsed)
+	}
+	if len(parsed.IsNull) != 1 || parsed.Page != 3 || parsed.PageSize != 10 {
+		t.Fatalf("Unexpected filter from %s: %+v", link, parsed)
+	}
+}
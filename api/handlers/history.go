@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"junior-test/pkg/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxActorLength - ограничение длины автора изменения (столбец people_history.actor).
+const maxActorLength = 100
+
+// adminActor - автор изменений, выполненных с токеном администратора.
+const adminActor = "admin"
+
+// actor возвращает автора изменения для истории. Автором считается только
+// подтвержденная личность: администратор (по токену) или IP-адрес клиента.
+// Заголовок X-Actor клиент может указать произвольно, поэтому он сохраняется
+// лишь как подсказка рядом с IP-адресом: "10.0.0.1 (X-Actor: Oleg)".
+func (h *PeopleHandler) actor(c *gin.Context) string {
+	if h.isAdmin(c) {
+		return adminActor
+	}
+
+	name := c.ClientIP()
+	hint := strings.TrimSpace(c.GetHeader("X-Actor"))
+	if hint == "" {
+		return name
+	}
+
+	name += " (X-Actor: " + hint
+	if utf8.RuneCountInString(name) >= maxActorLength {
+		name = string([]rune(name)[:maxActorLength-1])
+	}
+	return name + ")"
+}
+
+// GetPersonHistory возвращает историю изменений записи постранично, сначала новые.
+// История доступна и после удаления записи, в том числе окончательного.
+func (h *PeopleHandler) GetPersonHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	errs := map[string]string{}
+	page, pageSize := 1, defaultPageSize
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"page", &page}, {"pagesize", &pageSize}} {
+		if raw, ok := c.GetQuery(param.name); ok {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				errs[param.name] = "must be a positive integer"
+				continue
+			}
+			*param.value = n
+		}
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination", "details": errs})
+		return
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	items, total, err := h.Repository.GetPersonHistory(id, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
+		return
+	}
+	if total == 0 {
+		// Записи, созданные до появления истории, могут не иметь ее вовсе
+		person, err := h.Repository.GetPersonIncludingDeleted(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+			return
+		}
+		if person == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+			return
+		}
+	}
+	if items == nil {
+		items = []*types.PersonHistory{}
+	}
+
+	history := types.PersonHistoryPage{
+		Items:      items,
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}
+	if history.Page < history.TotalPages {
+		history.Next = pageLink(c.Request.URL.Path, c.Request.URL.Query(), page+1, pageSize)
+	}
+	if history.Page > 1 && history.TotalPages > 0 {
+		prev := page - 1
+		if prev > history.TotalPages {
+			prev = history.TotalPages
+		}
+		history.Prev = pageLink(c.Request.URL.Path, c.Request.URL.Query(), prev, pageSize)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
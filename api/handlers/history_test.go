@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestActor(t *testing.T) {
+	h := &PeopleHandler{AdminToken: "secret"}
+	newContext := func(headers map[string]string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/v1/people", nil)
+		c.Request.RemoteAddr = "10.0.0.1:1234"
+		for key, value := range headers {
+			c.Request.Header.Set(key, value)
+		}
+		return c
+	}
+
+	// X-Actor не подтверждается и не может выдать себя за другого автора
+	if actor := h.actor(newContext(map[string]string{"X-Actor": "admin"})); actor != "10.0.0.1 (X-Actor: admin)" {
+		t.Fatalf("Unexpected actor: %q", actor)
+	}
+	if actor := h.actor(newContext(map[string]string{"X-Admin-Token": "secret", "X-Actor": "Oleg"})); actor != adminActor {
+		t.Fatalf("Expected admin actor, got %q", actor)
+	}
+	if actor := h.actor(newContext(nil)); actor != "10.0.0.1" {
+		t.Fatalf("Expected client IP, got %q", actor)
+	}
+
+	actor := h.actor(newContext(map[string]string{"X-Actor": strings.Repeat("я", 200)}))
+	if utf8.RuneCountInString(actor) != maxActorLength || !strings.HasSuffix(actor, ")") {
+		t.Fatalf("Expected actor to be truncated to %d runes, got %q", maxActorLength, actor)
+	}
+}
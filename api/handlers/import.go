@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"junior-test/pkg/importer"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportPeople загружает людей из CSV или NDJSON в теле запроса либо в поле file
+// формы multipart/form-data. Формат задается параметром format, а если он не указан -
+// по Content-Type или расширению файла. Режим обогащения задается параметром enrich
+// (queue по умолчанию, sync или none). В ответе - отчет с отклоненными строками.
+func (h *PeopleHandler) ImportPeople(c *gin.Context) {
+	mode := c.DefaultQuery("enrich", importer.EnrichQueue)
+	if !importer.ValidEnrichMode(mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'enrich' must be one of queue, sync, none"})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	format := c.Query("format")
+
+	if mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+		// Файл читается из формы потоково, без сохранения во временный файл
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read multipart form"})
+			return
+		}
+		body = nil
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() == "file" {
+				body = part
+				if format == "" {
+					format = importer.FormatFromName(part.FileName())
+				}
+				if format == "" {
+					format = importer.FormatFromContentType(part.Header.Get("Content-Type"))
+				}
+				break
+			}
+		}
+		if body == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'file' is required"})
+			return
+		}
+	} else if format == "" {
+		format = importer.FormatFromContentType(c.GetHeader("Content-Type"))
+	}
+
+	if !importer.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected csv or ndjson"})
+		return
+	}
+
+	im := &importer.Importer{
+		Repository: h.Repository,
+		Enrichers:  h.Enrichers,
+		Enrich:     mode,
+		Actor:      h.actor(c),
+	}
+	report, err := im.Run(c.Request.Context(), body, format)
+	if err != nil {
+		// Часть записей уже могла быть сохранена - отчет возвращается вместе с ошибкой
+		log.Printf("Error ImportPeople: %v", err)
+		if errors.Is(err, importer.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Import failed: " + err.Error(), "report": report})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import people", "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
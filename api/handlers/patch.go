@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	db "junior-test/db/models"
+	"junior-test/pkg/jsonpatch"
+	"log"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Типы содержимого для PATCH.
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// readOnlyFields - поля записи, которые возвращаются клиенту, но не изменяются через API.
+var readOnlyFields = map[string]bool{
+	"id": true, "age_count": true, "gender_probability": true, "nationalities": true,
+	"enrichment_status": true, "enrichment_fields": true, "created_at": true, "updated_at": true, "version": true, "deleted_at": true, "score": true,
+}
+
+// fieldLimits - максимальная длина текстовых полей (ограничения таблицы people).
+var fieldLimits = map[string]int{
+	"name": 50, "surname": 50, "patronymic": 50, "gender": 10, "nationality": 255,
+}
+
+// PatchPerson частично изменяет запись: JSON Merge Patch (RFC 7396, по умолчанию)
+// или JSON Patch (RFC 6902) при Content-Type: application/json-patch+json.
+// Значение null очищает поле.
+func (h *PeopleHandler) PatchPerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	current, version, err := h.Repository.GetPersonDocument(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+	if current == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if !checkIfMatch(c, version) {
+		return
+	}
+
+	var patched map[string]interface{}
+	switch c.ContentType() {
+	case jsonPatchContentType:
+		patched, err = jsonpatch.Apply(current, patch)
+	case mergePatchContentType, "application/json", "":
+		patched, err = jsonpatch.MergePatch(current, patch)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Supported content types: " + mergePatchContentType + ", " + jsonPatchContentType})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to apply patch: " + err.Error()})
+		return
+	}
+
+	h.savePersonDocument(c, id, version, current, patched)
+}
+
+// ReplacePerson полностью заменяет изменяемые поля записи: поля, отсутствующие
+// в теле запроса, очищаются. Поля name и surname обязательны.
+func (h *PeopleHandler) ReplacePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil || body == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode JSON request"})
+		return
+	}
+
+	// Тело может быть ранее полученной записью целиком - служебные поля игнорируем
+	for field := range body {
+		if readOnlyFields[field] {
+			delete(body, field)
+		}
+	}
+
+	current, version, err := h.Repository.GetPersonDocument(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+	if current == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if !checkIfMatch(c, version) {
+		return
+	}
+
+	h.savePersonDocument(c, id, version, current, body)
+}
+
+// savePersonDocument проверяет новый документ записи, сохраняет отличия
+// от текущего и отправляет обновленную запись. Изменения сохраняются, только
+// если запись все еще имеет версию version, из которой получен current.
+func (h *PeopleHandler) savePersonDocument(c *gin.Context, id, version int, current, patched map[string]interface{}) {
+	values, err := validatePersonDocument(patched)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes := map[string]interface{}{}
+	for _, column := range db.EditableColumns {
+		if !reflect.DeepEqual(current[column], patched[column]) {
+			changes[column] = values[column]
+		}
+	}
+
+	_, err = h.Repository.PatchPerson(id, changes, []int{version}, h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVersionMismatch) {
+		// Запись изменили между чтением и записью
+		if c.GetHeader("If-Match") != "" {
+			preconditionFailed(c)
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "Person was modified concurrently, retry the request"})
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Error PatchPerson: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update person"})
+		return
+	}
+
+	person, err := h.Repository.GetPersonByID(id)
+	if err != nil || person == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+
+	setETag(c, person.Version)
+	c.JSON(http.StatusOK, person)
+}
+
+// validatePersonDocument проверяет поля документа и приводит их к типам столбцов.
+// Отсутствующее поле соответствует NULL.
+func validatePersonDocument(doc map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	for field, value := range doc {
+		if readOnlyFields[field] {
+			return nil, fmt.Errorf("Field '%s' is read-only", field)
+		}
+		if _, ok := fieldLimits[field]; !ok && field != "age" {
+			return nil, fmt.Errorf("Unknown field '%s'", field)
+		}
+
+		// null очищает поле; для name и surname он отклоняется ниже
+		if value == nil {
+			values[field] = nil
+			continue
+		}
+
+		if field == "age" {
+			age, ok := value.(float64)
+			if !ok || age < 0 || age != math.Trunc(age) || age > math.MaxInt32 {
+				return nil, fmt.Errorf("Field 'age' must be a non-negative integer or null")
+			}
+			values[field] = int(age)
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("Field '%s' must be a string or null", field)
+		}
+		if utf8.RuneCountInString(text) > fieldLimits[field] {
+			return nil, fmt.Errorf("Field '%s' must be at most %d characters", field, fieldLimits[field])
+		}
+		values[field] = text
+	}
+
+	for _, field := range []string{"name", "surname"} {
+		if text, _ := values[field].(string); text == "" {
+			return nil, fmt.Errorf("Field '%s' is required and cannot be null", field)
+		}
+	}
+
+	for _, column := range db.EditableColumns {
+		if _, ok := values[column]; !ok {
+			values[column] = nil
+		}
+	}
+
+	return values, nil
+}
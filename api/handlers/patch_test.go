@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"junior-test/pkg/jsonpatch"
+	"testing"
+)
+
+func TestValidatePersonDocument(t *testing.T) {
+	values, err := validatePersonDocument(map[string]interface{}{
+		"name":    "Dmitriy",
+		"surname": "Ushakov",
+		"age":     0.0,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if values["age"] != 0 || values["patronymic"] != nil || values["gender"] != nil {
+		t.Fatalf("Unexpected values: %v", values)
+	}
+
+	invalid := []map[string]interface{}{
+		{"name": "Dmitriy"},
+		{"name": "Dmitriy", "surname": nil},
+		{"name": nil, "surname": "Ushakov"},
+		{"name": "Dmitriy", "surname": "Ushakov", "age": 1.5},
+		{"name": "Dmitriy", "surname": "Ushakov", "age": "42"},
+		{"name": "Dmitriy", "surname": "Ushakov", "gender": "much-too-long"},
+		{"name": "Dmitriy", "surname": "Ushakov", "id": 1.0},
+		{"name": "Dmitriy", "surname": "Ushakov", "nickname": "Dima"},
+	}
+	for _, doc := range invalid {
+		if _, err := validatePersonDocument(doc); err == nil {
+			t.Fatalf("Expected error for %v", doc)
+		}
+	}
+}
+
+func TestReplacePersonNull(t *testing.T) {
+	// Тело PUT декодируется так же, как в ReplacePerson
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"name":"A","surname":"B","patronymic":null,"age":null}`), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := validatePersonDocument(body)
+	if err != nil {
+		t.Fatalf("Expected null to clear the fields, got %v", err)
+	}
+	if values["patronymic"] != nil || values["age"] != nil || values["name"] != "A" {
+		t.Fatalf("Unexpected values: %v", values)
+	}
+}
+
+func TestJSONPatchNull(t *testing.T) {
+	current := map[string]interface{}{
+		"name": "Dmitriy", "surname": "Ushakov", "patronymic": "Vasilevich", "age": 42.0, "gender": "male", "nationality": "RU",
+	}
+
+	patched, err := jsonpatch.Apply(current, []byte(`[{"op":"replace","path":"/patronymic","value":null},{"op":"replace","path":"/age","value":null}]`))
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	values, err := validatePersonDocument(patched)
+	if err != nil {
+		t.Fatalf("Expected null to clear the fields, got %v", err)
+	}
+	if values["patronymic"] != nil || values["age"] != nil || values["gender"] != "male" {
+		t.Fatalf("Unexpected values: %v", values)
+	}
+
+	patched, err = jsonpatch.Apply(current, []byte(`[{"op":"replace","path":"/surname","value":null}]`))
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	if _, err := validatePersonDocument(patched); err == nil {
+		t.Fatal("Expected error for null surname")
+	}
+}
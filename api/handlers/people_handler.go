@@ -1,19 +1,39 @@
 package handlers
 
 import (
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
 	db "junior-test/db/models"
+	"junior-test/pkg/enrich"
 	"junior-test/pkg/types"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Размер страницы списка людей по умолчанию и максимально допустимый.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
 type PeopleHandler struct {
 	Repository *db.SQLPersonRepository
+	Enrichers  []enrich.Enricher // используются для синхронного обогащения (?sync=true)
+
+	// CursorSecret - ключ подписи курсоров keyset-пагинации.
+	CursorSecret []byte
+
+	// MaxBatchSize - максимальное количество людей в пакетном запросе (по умолчанию defaultMaxBatchSize).
+	MaxBatchSize int
+
+	// AdminToken - токен администратора (заголовок X-Admin-Token). Если пуст,
+	// административные операции недоступны.
+	AdminToken string
 }
 
 func (h *PeopleHandler) EnrichPerson(c *gin.Context) {
@@ -24,174 +44,48 @@ func (h *PeopleHandler) EnrichPerson(c *gin.Context) {
 		return
 	}
 
-	// Создаем каналы для получения результатов
-	ageCh := make(chan int)
-	genderCh := make(chan string)
-	nationalityCh := make(chan string)
-	errCh := make(chan error)
-
-	// Запускаем горутины для каждого запроса
-	go func() {
-		age, err := h.getAge(person.Name)
-		if err != nil {
-			errCh <- err
-			return
-		}
-		ageCh <- age
-	}()
-
-	go func() {
-		gender, err := h.getGender(person.Name)
-		if err != nil {
-			errCh <- err
-			return
-		}
-		genderCh <- gender
-	}()
-
-	go func() {
-		nationality, err := h.getNationality(person.Name)
-		if err != nil {
-			errCh <- err
-			return
-		}
-		nationalityCh <- nationality
-	}()
-
-	// Ожидание результатов из горутины
-	var Err error
-
-	for i := 0; i < 3; i++ {
-		select {
-		case age := <-ageCh:
-			person.Age = age
-		case gender := <-genderCh:
-			person.Gender = gender
-		case nationality := <-nationalityCh:
-			person.Nationality = nationality
-		case Err = <-errCh:
-			break
-		}
+	if err := types.ValidatePerson(&person); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if Err == nil {
-		_, err := h.Repository.CreatePerson(&person)
-		if err != nil {
-			log.Printf("Error CreatePerson: %v", err)
+	var id int
+	var err error
+
+	if c.Query("sync") == "true" {
+		// Синхронный режим: обогащаем сразу; запись, не обогащенную ни одним источником, не сохраняем
+		err = enrich.Run(c.Request.Context(), &person, h.Enrichers)
+		if enrich.ShouldDefer(err, &person) {
+			log.Printf("Error enriching person, queueing missing fields: %v", err)
+			id, err = h.Repository.CreatePendingPerson(&person, h.actor(c))
+		} else if err != nil {
+			log.Printf("Error enriching person: %v", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to enrich person"})
+			return
+		} else {
+			id, err = h.Repository.CreatePerson(&person, h.actor(c))
 		}
 	} else {
-		log.Printf("Error: %v", Err)
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Person enriched and created"})
-}
-
-func (h *PeopleHandler) getAge(name string) (int, error) {
-	// Формирование URL для запроса к API Agify
-	url := "https://api.agify.io/?name=" + name
-
-	// Отправка HTTP GET-запроса
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	// Обработка ответа
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("API request failed with status: %s", resp.Status)
-	}
-
-	// Распарсинг JSON-ответа
-	var data types.AgifyResponse
-
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return 0, err
-	}
-
-	// Проверка наличия возраста и корректного имени
-	if data.Count == 0 {
-		return 0, fmt.Errorf("Name is incorrect")
-	}
-
-	// Возврат возраста
-	return data.Age, nil
-}
-
-func (h *PeopleHandler) getGender(name string) (string, error) {
-	// Формирование URL для запроса к API Genderize
-	url := "https://api.genderize.io/?name=" + name
-
-	// Отправка HTTP GET-запроса
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Обработка ответа
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %s", resp.Status)
-	}
-
-	// Распарсинг JSON-ответа
-	var data types.GenderizeResponse
-
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return "", err
+		// Сохраняем запись сразу, обогащение выполнит пул воркеров из очереди
+		id, err = h.Repository.CreatePendingPerson(&person, h.actor(c))
 	}
 
-	// Проверка наличия пола и корректного имени
-	if data.Count == 0 {
-		return "", fmt.Errorf("Name is incorrect")
-	}
-
-	// Возврат пола
-	return data.Gender, nil
-}
-
-func (h *PeopleHandler) getNationality(name string) (string, error) {
-	// Формирование URL для запроса к API Nationalize
-	url := "https://api.nationalize.io/?name=" + name
-
-	// Отправка HTTP GET-запроса
-	resp, err := http.Get(url)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Обработка ответа
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %s", resp.Status)
-	}
-
-	// Распарсинг JSON-ответа
-	var data types.NationalizeResponse
-
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return "", err
-	}
-
-	// Проверка наличия национальности и корректного имени
-	if data.Count == 0 {
-		return "", fmt.Errorf("Name is incorrect")
+		log.Printf("Error CreatePerson: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create person"})
+		return
 	}
 
-	// Возврат национальности (берем национальность с наибольшей вероятностью)
-	maxProbability := 0.0
-	var nationality string
-	for _, country := range data.Country {
-		if country.Probability > maxProbability {
-			maxProbability = country.Probability
-			nationality = country.CountryID
-		}
+	created, err := h.Repository.GetPersonByID(id)
+	if err != nil || created == nil {
+		log.Printf("Error reading created person with ID %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
 	}
 
-	return nationality, nil
+	c.Header("Location", fmt.Sprintf("/api/v1/people/%d", id))
+	setETag(c, created.Version)
+	c.JSON(http.StatusCreated, created)
 }
 
 func (h *PeopleHandler) GetPersonByID(c *gin.Context) {
@@ -207,7 +101,12 @@ func (h *PeopleHandler) GetPersonByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
 		return
 	}
+	if person == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
 
+	setETag(c, person.Version)
 	c.JSON(http.StatusOK, person)
 }
 
@@ -228,14 +127,30 @@ func (h *PeopleHandler) UpdatePerson(c *gin.Context) {
 	}
 
 	// Вызываем функцию репозитория для обновления информации о человеке по ID.
-	err = h.Repository.UpdatePerson(id, &updatedPerson)
+	// Если передан If-Match, запись обновляется только при совпадении версии.
+	_, err = h.Repository.UpdatePerson(id, &updatedPerson, ifMatchVersions(c.Request.Header.Values("If-Match")), h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVersionMismatch) {
+		preconditionFailed(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update person"})
 		return
 	}
 
-	// Отправляем успешный ответ.
-	c.JSON(http.StatusOK, gin.H{"message": "Person updated successfully"})
+	// Отправляем обновленную запись.
+	person, err := h.Repository.GetPersonByID(id)
+	if err != nil || person == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		return
+	}
+
+	setETag(c, person.Version)
+	c.JSON(http.StatusOK, person)
 }
 
 func (h *PeopleHandler) DeletePerson(c *gin.Context) {
@@ -248,7 +163,15 @@ func (h *PeopleHandler) DeletePerson(c *gin.Context) {
 	}
 
 	// Вызываем функцию репозитория для удаления информации о человеке по ID.
-	err = h.Repository.DeletePerson(id)
+	err = h.Repository.DeletePerson(id, ifMatchVersions(c.Request.Header.Values("If-Match")), h.actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return
+	}
+	if errors.Is(err, db.ErrVersionMismatch) {
+		preconditionFailed(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete person"})
 		return
@@ -260,10 +183,30 @@ func (h *PeopleHandler) DeletePerson(c *gin.Context) {
 
 func (h *PeopleHandler) FilterListPeople(c *gin.Context) {
 	// Парсинг параметров запроса, таких как фильтр и параметры пагинации
-	var filter types.PersonFilter
+	filter, errs := parsePersonFilter(c.Request)
+	if errs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": errs})
+		return
+	}
 
-	if err := c.BindJSON(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode JSON request"})
+	// Значения пагинации по умолчанию и ограничение размера страницы
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize > maxPageSize {
+		filter.PageSize = maxPageSize
+	}
+
+	if filter.IncludeDeleted && !h.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Filter include_deleted is available only to administrators"})
+		return
+	}
+
+	if filter.Cursor != nil {
+		h.listPeopleByCursor(c, filter)
 		return
 	}
 
@@ -274,6 +217,56 @@ func (h *PeopleHandler) FilterListPeople(c *gin.Context) {
 		return
 	}
 
-	// Отправка списка людей в формате JSON
-	c.JSON(http.StatusOK, people)
+	total, err := h.Repository.CountPeople(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve people"})
+		return
+	}
+
+	if people == nil {
+		people = []*types.Person{}
+	}
+
+	page := types.PersonPage{
+		Items:      people,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		Total:      total,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	}
+	// Ссылки строятся по разобранному фильтру: условия из JSON-тела запроса тоже сохраняются
+	query := filterQuery(filter)
+	if page.Page < page.TotalPages {
+		page.Next = pageLink(c.Request.URL.Path, query, page.Page+1, page.PageSize)
+	}
+	if page.Page > 1 && page.TotalPages > 0 {
+		// Для страницы за пределами списка предыдущей считается последняя
+		prev := page.Page - 1
+		if prev > page.TotalPages {
+			prev = page.TotalPages
+		}
+		page.Prev = pageLink(c.Request.URL.Path, query, prev, page.PageSize)
+	}
+
+	// Отправка страницы списка людей в формате JSON
+	c.JSON(http.StatusOK, page)
+}
+
+// pageLink возвращает ссылку на страницу page с остальными параметрами query.
+func pageLink(path string, query url.Values, page, pageSize int) *string {
+	query = cloneQuery(query)
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pagesize", strconv.Itoa(pageSize))
+
+	link := path + "?" + query.Encode()
+	return &link
+}
+
+// cloneQuery копирует параметры, чтобы ссылки на разные страницы не влияли друг на друга.
+func cloneQuery(query url.Values) url.Values {
+	clone := make(url.Values, len(query))
+	for key, values := range query {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
 }
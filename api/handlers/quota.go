@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEnrichmentQuota возвращает состояние квот внешних API обогащения,
+// известное по заголовкам их последних ответов.
+func (h *PeopleHandler) GetEnrichmentQuota(c *gin.Context) {
+	quotas := []types.ProviderQuota{}
+	for _, e := range h.Enrichers {
+		if reporter, ok := e.(enrich.QuotaReporter); ok {
+			quotas = append(quotas, reporter.Quota())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"providers": quotas})
+}
@@ -2,9 +2,7 @@ package routes
 
 import (
 	"junior-test/api/handlers"
-	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,36 +19,51 @@ func RunServer(handler *handlers.PeopleHandler) {
 }
 
 func SetupPeopleRoutes(r *gin.Engine, handler *handlers.PeopleHandler) {
+	// Ресурс people
+	people := r.Group("/api/v1/people")
+	{
+		people.POST("", handler.EnrichPerson)
+		people.POST("/batch", handler.CreatePeopleBatch)
+		people.POST("/import", handler.ImportPeople)
+		people.GET("", handler.FilterListPeople)
+		people.GET("/export", handler.ExportPeople)
+		people.GET("/:id", handler.GetPersonByID)
+		people.PUT("/:id", handler.ReplacePerson)
+		people.PATCH("/:id", handler.PatchPerson)
+		people.DELETE("/:id", handler.DeletePerson)
+		people.GET("/:id/history", handler.GetPersonHistory)
+		people.POST("/:id/restore", handler.RestorePerson)
+		people.POST("/:id/enrich", handler.EnrichMissingFields)
+		people.DELETE("/:id/purge", handler.RequireAdmin, handler.PurgePerson)
+	}
 
-	// Маршрут для добавления человека
-	r.POST("/add", handler.EnrichPerson)
+	// Состояние внешних API обогащения
+	r.GET("/api/v1/enrichment/quota", handler.GetEnrichmentQuota)
 
-	// Маршрут для получения информации о человеке по ID
-	r.GET("/getperson/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		idInt, err := strconv.Atoi(id)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
-			return
-		}
+	// Устаревшие маршруты, оставлены для совместимости со старыми клиентами
+	legacy := r.Group("/", deprecated("/api/v1/people"))
+	{
+		// Маршрут для добавления человека
+		legacy.POST("/add", handler.EnrichPerson)
 
-		// Здесь вызываем функцию репозитория для получения информации о человеке по ID
-		person, err := handler.Repository.GetPersonByID(idInt)
+		// Маршрут для получения информации о человеке по ID
+		legacy.GET("/getperson/:id", handler.GetPersonByID)
 
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
-			return
-		}
+		legacy.PUT("/update/:id", handler.UpdatePerson)
 
-		c.JSON(http.StatusOK, person)
-	})
+		// Маршрут для удаления человека по ID
+		legacy.DELETE("/delete/:id", handler.DeletePerson)
 
-	r.PUT("/update/:id", handler.UpdatePerson)
-
-	// Маршрут для удаления человека по ID
-	r.DELETE("/delete/:id", handler.DeletePerson)
-
-	// Маршрут для получения списка людей с параметрами пагинации и фильтра
-	r.GET("/filter", handler.FilterListPeople)
+		// Маршрут для получения списка людей с параметрами пагинации и фильтра
+		legacy.GET("/filter", handler.FilterListPeople)
+	}
+}
 
+// deprecated помечает ответы устаревших маршрутов заголовками Deprecation и Link (RFC 8594).
+func deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
 }
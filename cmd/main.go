@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/json"
+	"flag"
 	"junior-test/api/handlers"
 	"junior-test/api/routes"
 	"junior-test/db"
 	dbModels "junior-test/db/models"
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/importer"
+	"junior-test/pkg/worker"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -52,12 +60,88 @@ func main() {
 		log.Fatal("Failed to initialize database after 5 attempts")
 	}
 
+	// Ручное управление миграциями: "main migrate up" или "main migrate down [N]"
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(database, os.Args[2:])
+		return
+	}
+
+	// Применение миграций схемы БД
+	if err := db.MigrateUp(database); err != nil {
+		log.Fatalf("Failed to apply migrations: %v", err)
+	}
+
 	// Создание экземпляра репозитория
 	repository := dbModels.NewSQLPersonRepository(database)
 
+	// Заполнение ключей нечеткого поиска для записей, созданных до их появления
+	if err := repository.BackfillSearchKeys(); err != nil {
+		log.Fatalf("Failed to fill search keys: %v", err)
+	}
+
+	// Кэш ответов API обогащения: в памяти и, если ENRICH_CACHE=postgres, в таблице enrichment_cache
+	cacheTTL := getEnvDuration("ENRICH_CACHE_TTL", 24*time.Hour)
+	var cacheStore *dbModels.SQLEnrichmentCache
+	var cache *enrich.Cache
+	switch mode := getEnv("ENRICH_CACHE", "postgres"); mode {
+	case "postgres":
+		cacheStore = dbModels.NewSQLEnrichmentCache(database)
+		cache = enrich.NewCache(cacheTTL, getEnvInt("ENRICH_CACHE_SIZE", 10000), cacheStore)
+	case "memory":
+		cache = enrich.NewCache(cacheTTL, getEnvInt("ENRICH_CACHE_SIZE", 10000), nil)
+	case "off":
+	default:
+		log.Fatalf("Unknown ENRICH_CACHE %q, expected postgres, memory or off", mode)
+	}
+
+	// Источники обогащения данных (адреса и ключи платного тарифа можно задать в .env)
+	httpClient := &http.Client{}
+	enrichers := []enrich.Enricher{
+		enrich.NewAgify(getEnv("AGIFY_URL", enrich.DefaultAgifyURL), httpClient,
+			enrich.WithCache(cache), enrich.WithAPIKey(os.Getenv("AGIFY_API_KEY")), enrich.WithCountry(os.Getenv("AGIFY_COUNTRY_ID")),
+			enrich.WithPolicy(enrichPolicy("AGIFY"))),
+		enrich.NewGenderize(getEnv("GENDERIZE_URL", enrich.DefaultGenderizeURL), httpClient,
+			enrich.WithCache(cache), enrich.WithAPIKey(os.Getenv("GENDERIZE_API_KEY")), enrich.WithCountry(os.Getenv("GENDERIZE_COUNTRY_ID")),
+			enrich.WithPolicy(enrichPolicy("GENDERIZE"))),
+		enrich.NewNationalize(getEnv("NATIONALIZE_URL", enrich.DefaultNationalizeURL), httpClient,
+			enrich.WithCache(cache), enrich.WithAPIKey(os.Getenv("NATIONALIZE_API_KEY")), enrich.WithPolicy(enrichPolicy("NATIONALIZE"))),
+	}
+
+	// Импорт людей из файла: "main import [-format csv|ndjson] [-enrich queue|sync|none] [-batch N] FILE"
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImportCommand(repository, enrichers, os.Args[2:])
+		return
+	}
+
+	// Запуск пула воркеров, обрабатывающих очередь обогащения
+	pool := &worker.EnrichmentPool{
+		Repository:   repository,
+		Enrichers:    enrichers,
+		Workers:      getEnvInt("ENRICH_WORKERS", 4),
+		MaxAttempts:  getEnvInt("ENRICH_MAX_ATTEMPTS", 5),
+		PollInterval: time.Second,
+		Lease:        time.Minute,
+		RetryDelay:   5 * time.Second,
+	}
+	go pool.Run(context.Background())
+
+	// Окончательное удаление записей после истечения срока хранения
+	purger := &worker.Purger{
+		Repository: repository,
+		Retention:  getEnvDuration("PURGE_RETENTION", 30*24*time.Hour),
+		Interval:   getEnvDuration("PURGE_INTERVAL", time.Hour),
+		Cache:      cacheStore,
+		CacheTTL:   cacheTTL,
+	}
+	go purger.Run(context.Background())
+
 	// Создание экземпляра PeopleHandler с передачей репозитория
 	handler := &handlers.PeopleHandler{
-		Repository: repository,
+		Repository:   repository,
+		Enrichers:    enrichers,
+		CursorSecret: cursorSecret(),
+		AdminToken:   os.Getenv("ADMIN_TOKEN"),
+		MaxBatchSize: getEnvInt("BATCH_MAX_SIZE", 100),
 	}
 
 	// Запуск сервера
@@ -65,3 +149,141 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+httpPort, nil))
 
 }
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию.
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию.
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration возвращает длительность из переменной окружения (например, "720h")
+// или значение по умолчанию.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// enrichPolicy возвращает настройки надежности запросов к источнику: переменные
+// с префиксом источника (AGIFY_TIMEOUT) переопределяют общие (ENRICH_TIMEOUT).
+func enrichPolicy(provider string) enrich.Policy {
+	setting := func(name string) string { return provider + "_" + name }
+	policy := enrich.DefaultPolicy
+
+	policy.Timeout = getEnvDuration("ENRICH_TIMEOUT", policy.Timeout)
+	policy.Timeout = getEnvDuration(setting("TIMEOUT"), policy.Timeout)
+	policy.MaxRetries = getEnvInt("ENRICH_RETRIES", policy.MaxRetries)
+	policy.MaxRetries = getEnvInt(setting("RETRIES"), policy.MaxRetries)
+	policy.RetryDelay = getEnvDuration("ENRICH_RETRY_DELAY", policy.RetryDelay)
+	policy.RetryDelay = getEnvDuration(setting("RETRY_DELAY"), policy.RetryDelay)
+	policy.MaxRetryDelay = getEnvDuration("ENRICH_RETRY_MAX_DELAY", policy.MaxRetryDelay)
+	policy.MaxRetryDelay = getEnvDuration(setting("RETRY_MAX_DELAY"), policy.MaxRetryDelay)
+	policy.BreakerThreshold = getEnvInt("ENRICH_BREAKER_THRESHOLD", policy.BreakerThreshold)
+	policy.BreakerThreshold = getEnvInt(setting("BREAKER_THRESHOLD"), policy.BreakerThreshold)
+	policy.BreakerCooldown = getEnvDuration("ENRICH_BREAKER_COOLDOWN", policy.BreakerCooldown)
+	policy.BreakerCooldown = getEnvDuration(setting("BREAKER_COOLDOWN"), policy.BreakerCooldown)
+
+	return policy
+}
+
+// runMigrateCommand применяет или откатывает миграции по аргументам командной строки.
+func runMigrateCommand(database *sql.DB, args []string) {
+	if len(args) == 0 || args[0] == "up" {
+		if err := db.MigrateUp(database); err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		return
+	}
+
+	if args[0] != "down" {
+		log.Fatalf("Unknown migrate command %q, expected up or down", args[0])
+	}
+
+	steps := 1
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid number of steps: %q", args[1])
+		}
+		steps = n
+	}
+
+	if err := db.MigrateDown(database, steps); err != nil {
+		log.Fatalf("Failed to roll back migrations: %v", err)
+	}
+}
+
+// runImportCommand загружает людей из CSV или NDJSON файла (или stdin, если FILE равен "-")
+// и выводит отчет об импорте в формате JSON.
+func runImportCommand(repository *dbModels.SQLPersonRepository, enrichers []enrich.Enricher, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "формат файла: csv или ndjson (по умолчанию определяется по расширению)")
+	mode := flags.String("enrich", importer.EnrichQueue, "обогащение: queue (очередь), sync (сразу) или none (без обогащения)")
+	batchSize := flags.Int("batch", importer.DefaultBatchSize, "количество записей, сохраняемых одним запросом")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: main import [-format csv|ndjson] [-enrich queue|sync|none] [-batch N] FILE")
+	}
+
+	input := os.Stdin
+	name := flags.Arg(0)
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("Failed to open import file: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	if *format == "" {
+		*format = importer.FormatFromName(name)
+	}
+
+	im := &importer.Importer{
+		Repository: repository,
+		Enrichers:  enrichers,
+		Enrich:     *mode,
+		BatchSize:  *batchSize,
+		Actor:      "import",
+	}
+	report, err := im.Run(context.Background(), input, *format)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
+
+// cursorSecret возвращает ключ подписи курсоров пагинации из CURSOR_SECRET.
+// Если ключ не задан, генерируется случайный: курсоры перестанут быть
+// действительными после перезапуска и не будут приниматься другими репликами.
+func cursorSecret() []byte {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	log.Println("CURSOR_SECRET is not set, using a random key for pagination cursors")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate cursor secret: %v", err)
+	}
+	return secret
+}
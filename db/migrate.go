@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockKey - ключ advisory-блокировки, под которой выполняются миграции,
+// чтобы несколько реплик приложения не применяли их одновременно.
+const migrationLockKey = 7_134_215_301
+
+// Имена файлов миграций: 001_init.up.sql, 001_init.down.sql или 001_init.sql (только up).
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+// migration описывает одну версию схемы.
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum возвращает контрольную сумму up-скрипта, по которой обнаруживается
+// изменение уже примененной миграции.
+func (m migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadMigrations читает миграции из каталога dir и сортирует их по версии.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			m.Down = string(content)
+		} else {
+			m.Up = string(content)
+		}
+	}
+
+	var migrations []migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// MigrateUp применяет все еще не примененные миграции.
+func MigrateUp(db *sql.DB) error {
+	return withMigrationLock(db, func(conn *sql.Conn, migrations []migration, applied map[int]string) error {
+		for _, m := range migrations {
+			checksum, ok := applied[m.Version]
+			if ok {
+				if checksum != m.Checksum() {
+					return fmt.Errorf("migration %d_%s was changed after it had been applied", m.Version, m.Name)
+				}
+				continue
+			}
+
+			err := runMigration(conn, m.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)", m.Version, m.Name, m.Checksum())
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		return nil
+	})
+}
+
+// MigrateDown откатывает steps последних примененных миграций.
+func MigrateDown(db *sql.DB, steps int) error {
+	return withMigrationLock(db, func(conn *sql.Conn, migrations []migration, applied map[int]string) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+			}
+
+			err := runMigration(conn, m.Down, "DELETE FROM schema_migrations WHERE version = $1", m.Version)
+			if err != nil {
+				return fmt.Errorf("rollback %d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// withMigrationLock захватывает advisory-блокировку на отдельном соединении,
+// создает таблицу schema_migrations и передает в fn список миграций и
+// контрольные суммы уже примененных версий.
+func withMigrationLock(db *sql.DB, fn func(conn *sql.Conn, migrations []migration, applied map[int]string) error) error {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Advisory-блокировка привязана к сессии, поэтому все запросы идут через одно соединение
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return err
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return fn(conn, migrations, applied)
+}
+
+// runMigration выполняет скрипт и запись в schema_migrations в одной транзакции.
+func runMigration(conn *sql.Conn, script string, bookkeeping string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
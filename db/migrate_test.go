@@ -0,0 +1,62 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"migrations/002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"migrations/001_first.sql":       {Data: []byte("CREATE TABLE a ();")},
+		"migrations/README.md":           {Data: []byte("not a migration")},
+	}
+
+	migrations, err := loadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+
+	if migrations[0].Version != 1 || migrations[0].Name != "first" || migrations[0].Down != "" {
+		t.Fatalf("Unexpected first migration: %+v", migrations[0])
+	}
+
+	if migrations[1].Version != 2 || migrations[1].Up != "CREATE TABLE b ();" || migrations[1].Down != "DROP TABLE b;" {
+		t.Fatalf("Unexpected second migration: %+v", migrations[1])
+	}
+
+	if migrations[0].Checksum() == migrations[1].Checksum() {
+		t.Fatal("Different scripts must have different checksums")
+	}
+}
+
+func TestLoadMigrationsWithoutUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+
+	if _, err := loadMigrations(fsys, "migrations"); err == nil {
+		t.Fatal("Expected error for migration without up script")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("Migration versions must be sequential: got %d at position %d", m.Version, i)
+		}
+		if m.Down == "" {
+			t.Fatalf("Migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS people_nationalities;
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS age_count,
+    DROP COLUMN IF EXISTS gender_probability;
@@ -0,0 +1,11 @@
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS age_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS gender_probability DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS people_nationalities (
+    person_id INT NOT NULL REFERENCES people (id) ON DELETE CASCADE,
+    rank INT NOT NULL,
+    country_id VARCHAR (2) NOT NULL,
+    probability DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (person_id, rank)
+);
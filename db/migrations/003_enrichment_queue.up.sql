@@ -0,0 +1,15 @@
+-- Уже существующие записи были обогащены синхронно
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR (10) NOT NULL DEFAULT 'complete';
+
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL REFERENCES people (id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS enrichment_jobs_run_at_idx ON enrichment_jobs (run_at);
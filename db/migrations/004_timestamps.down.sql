@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS people_set_updated_at ON people;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS updated_at;
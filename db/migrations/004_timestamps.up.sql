@@ -0,0 +1,16 @@
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- updated_at обновляется автоматически при любом изменении записи
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS people_set_updated_at ON people;
+CREATE TRIGGER people_set_updated_at
+    BEFORE UPDATE ON people
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
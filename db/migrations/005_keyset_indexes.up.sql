@@ -0,0 +1,4 @@
+-- Индексы для keyset-пагинации: ключ сортировки + id для однозначного порядка
+CREATE INDEX IF NOT EXISTS people_name_id_idx ON people (name, id);
+CREATE INDEX IF NOT EXISTS people_surname_id_idx ON people (surname, id);
+CREATE INDEX IF NOT EXISTS people_created_at_id_idx ON people (created_at, id);
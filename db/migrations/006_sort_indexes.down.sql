@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS people_age_sort_idx;
+DROP INDEX IF EXISTS people_updated_at_id_idx;
@@ -0,0 +1,4 @@
+-- Индексы под выражения сортировки: выражение должно совпадать с sortColumns,
+-- иначе индекс не используется (NULL заменяется значением по умолчанию)
+CREATE INDEX IF NOT EXISTS people_age_sort_idx ON people ((COALESCE(age, 0)), id);
+CREATE INDEX IF NOT EXISTS people_updated_at_id_idx ON people (updated_at, id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS people_search_vector_idx;
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS people_name_trgm_idx;
+DROP INDEX IF EXISTS people_surname_trgm_idx;
+DROP INDEX IF EXISTS people_patronymic_trgm_idx;
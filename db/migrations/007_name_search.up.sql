@@ -0,0 +1,14 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram-индексы для поиска по префиксу и подстроке (LOWER(...) LIKE ...)
+CREATE INDEX IF NOT EXISTS people_name_trgm_idx ON people USING gin (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_surname_trgm_idx ON people USING gin (LOWER(surname) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_patronymic_trgm_idx ON people USING gin (LOWER(patronymic) gin_trgm_ops);
+
+-- Полнотекстовый поиск сразу по имени, фамилии и отчеству
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', name || ' ' || surname || ' ' || COALESCE(patronymic, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS people_search_vector_idx ON people USING gin (search_vector);
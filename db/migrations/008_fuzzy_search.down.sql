@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS people_search_key_trgm_idx;
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS search_key;
@@ -0,0 +1,6 @@
+-- Нормализованное (транслитерированное) ФИО для нечеткого поиска.
+-- Заполняется приложением, см. pkg/translit.
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS search_key TEXT;
+
+CREATE INDEX IF NOT EXISTS people_search_key_trgm_idx ON people USING gin (search_key gin_trgm_ops);
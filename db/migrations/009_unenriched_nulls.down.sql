@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS people_gender_idx;
+DROP INDEX IF EXISTS people_nationality_idx;
+
+UPDATE people SET age = COALESCE(age, 0), gender = COALESCE(gender, ''), nationality = COALESCE(nationality, '')
+    WHERE enrichment_status <> 'complete';
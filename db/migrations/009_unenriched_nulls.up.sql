@@ -0,0 +1,7 @@
+-- Необогащенные записи хранят NULL вместо нулевых значений,
+-- чтобы их можно было найти фильтром null
+UPDATE people SET age = NULL, gender = NULL, nationality = NULL
+    WHERE enrichment_status <> 'complete';
+
+CREATE INDEX IF NOT EXISTS people_gender_idx ON people (LOWER(gender));
+CREATE INDEX IF NOT EXISTS people_nationality_idx ON people (LOWER(nationality));
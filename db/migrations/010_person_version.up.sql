@@ -0,0 +1,2 @@
+-- Версия записи для оптимистичной блокировки (ETag / If-Match)
+ALTER TABLE people ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
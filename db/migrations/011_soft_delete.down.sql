@@ -0,0 +1,4 @@
+DELETE FROM people WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS people_deleted_at_idx;
+ALTER TABLE people DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+-- История изменений записей: снимки до и после изменения, автор и время.
+-- Внешнего ключа на people нет: история сохраняется и после окончательного удаления записи.
+CREATE TABLE IF NOT EXISTS people_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,
+    action VARCHAR (10) NOT NULL,
+    actor VARCHAR (100) NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS people_history_person_idx ON people_history (person_id, id);
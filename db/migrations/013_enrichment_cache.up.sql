@@ -0,0 +1,9 @@
+-- Кэш ответов API обогащения, общий для всех реплик.
+-- name - нормализованное имя.
+CREATE TABLE IF NOT EXISTS enrichment_cache (
+    provider VARCHAR (20) NOT NULL,
+    name VARCHAR (100) NOT NULL,
+    response JSONB NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, name)
+);
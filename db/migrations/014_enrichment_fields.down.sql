@@ -0,0 +1,5 @@
+-- Частично обогащенные записи считаются необогащенными
+UPDATE people SET enrichment_status = 'failed' WHERE enrichment_status = 'partial';
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS enrichment_fields;
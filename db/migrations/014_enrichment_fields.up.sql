@@ -0,0 +1,8 @@
+-- Статус обогащения каждого поля: поля обогащаются независимо друг от друга,
+-- а запись получает статус partial, если обогащена только часть полей.
+ALTER TABLE people
+    ADD COLUMN IF NOT EXISTS enrichment_fields JSONB NOT NULL DEFAULT '{}';
+
+UPDATE people
+    SET enrichment_fields = jsonb_build_object('age', enrichment_status, 'gender', enrichment_status, 'nationality', enrichment_status)
+    WHERE enrichment_fields = '{}';
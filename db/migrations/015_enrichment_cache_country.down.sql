@@ -0,0 +1,8 @@
+-- Без столбца ответы с подсказкой страны не отличить от ответов без нее
+DELETE FROM enrichment_cache WHERE country_id <> '';
+
+ALTER TABLE enrichment_cache DROP CONSTRAINT IF EXISTS enrichment_cache_pkey;
+ALTER TABLE enrichment_cache ADD PRIMARY KEY (provider, name);
+
+ALTER TABLE enrichment_cache
+    DROP COLUMN IF EXISTS country_id;
@@ -0,0 +1,7 @@
+-- Подсказка страны (параметр country_id API) входит в ключ кэша:
+-- ответы для разных стран хранятся отдельно, '' - без подсказки.
+ALTER TABLE enrichment_cache
+    ADD COLUMN IF NOT EXISTS country_id VARCHAR (2) NOT NULL DEFAULT '';
+
+ALTER TABLE enrichment_cache DROP CONSTRAINT IF EXISTS enrichment_cache_pkey;
+ALTER TABLE enrichment_cache ADD PRIMARY KEY (provider, name, country_id);
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"junior-test/pkg/types"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// createPeopleQuery вставляет записи из массивов, переданных параметрами, поэтому
+// число параметров не зависит от размера пачки. ID выдаются заранее в CTE input:
+// CTE с nextval материализуется один раз, и по нему каждой вставленной строке
+// сопоставляется порядковый номер входной записи.
+const createPeopleQuery = `WITH input AS (
+	SELECT nextval(pg_get_serial_sequence('people', 'id')) AS id, t.*
+	FROM unnest($1::text[], $2::text[], $3::text[], $4::int[], $5::int[], $6::text[], $7::float8[], $8::text[], $9::text[], $10::jsonb[], $11::text[])
+		WITH ORDINALITY AS t(name, surname, patronymic, age, age_count, gender, gender_probability, nationality, enrichment_status, enrichment_fields, search_key, ord)
+), inserted AS (
+	INSERT INTO people (id, name, surname, patronymic, age, age_count, gender, gender_probability, nationality, enrichment_status, enrichment_fields, search_key)
+	SELECT id, name, surname, patronymic, age, age_count, gender, gender_probability, nationality, enrichment_status, enrichment_fields, search_key FROM input
+	RETURNING id, created_at, updated_at, version
+)
+SELECT input.ord, inserted.id, inserted.created_at, inserted.updated_at, inserted.version
+FROM inserted JOIN input ON input.id = inserted.id`
+
+// CreatePeople сохраняет несколько записей одним INSERT вместе с их
+// национальностями и историей создания в одной транзакции. Для записей
+// со статусом pending в той же транзакции ставятся задания на обогащение.
+// Заполняет ID, CreatedAt, UpdatedAt, Version, EnrichmentStatus и EnrichmentFields каждой записи.
+func (r *SQLPersonRepository) CreatePeople(people []*types.Person, actor string) error {
+	if len(people) == 0 {
+		return nil
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	n := len(people)
+	names, surnames, patronymics := make([]string, n), make([]string, n), make([]string, n)
+	ages, ageCounts := make([]sql.NullInt64, n), make([]int64, n)
+	genders, probabilities := make([]sql.NullString, n), make([]float64, n)
+	nationalities, statuses := make([]sql.NullString, n), make([]string, n)
+	fields, keys := make([]string, n), make([]string, n)
+	for i, person := range people {
+		enrichment, age, gender, nationality := enrichedValues(person)
+		names[i], surnames[i], patronymics[i] = person.Name, person.Surname, person.Patronymic
+		if age != nil {
+			ages[i] = sql.NullInt64{Int64: int64(person.Age), Valid: true}
+		}
+		if gender != nil {
+			genders[i] = sql.NullString{String: person.Gender, Valid: true}
+		}
+		if nationality != nil {
+			nationalities[i] = sql.NullString{String: person.Nationality, Valid: true}
+		}
+		ageCounts[i], probabilities[i] = int64(person.AgeCount), person.GenderProbability
+		statuses[i], fields[i], keys[i] = person.EnrichmentStatus, string(enrichment), searchKey(person)
+	}
+
+	result, err := tx.Query(createPeopleQuery, pq.Array(names), pq.Array(surnames), pq.Array(patronymics), pq.Array(ages), pq.Array(ageCounts),
+		pq.Array(genders), pq.Array(probabilities), pq.Array(nationalities), pq.Array(statuses), pq.Array(fields), pq.Array(keys))
+	if err != nil {
+		log.Printf("Error while inserting %d people: %v", len(people), err)
+		return err
+	}
+
+	inserted := 0
+	for result.Next() {
+		var ord int
+		var p types.Person
+		if err := result.Scan(&ord, &p.ID, &p.CreatedAt, &p.UpdatedAt, &p.Version); err != nil {
+			result.Close()
+			log.Printf("Error scanning inserted person: %v", err)
+			return err
+		}
+		person := people[ord-1]
+		person.ID, person.CreatedAt, person.UpdatedAt, person.Version = p.ID, p.CreatedAt, p.UpdatedAt, p.Version
+		inserted++
+	}
+	result.Close()
+	if err := result.Err(); err != nil {
+		return err
+	}
+	if inserted != len(people) {
+		return fmt.Errorf("inserted %d of %d people", inserted, len(people))
+	}
+
+	ids := make([]int64, len(people))
+	var pending []int64
+	for i, person := range people {
+		ids[i] = int64(person.ID)
+		if person.EnrichmentStatus == types.EnrichmentPending {
+			pending = append(pending, ids[i])
+		}
+	}
+
+	if err = insertPeopleNationalities(tx, people); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO people_history (person_id, action, actor, after) SELECT id, $1, $2, " + personSnapshot + " FROM people WHERE id = ANY($3)"
+	if _, err = tx.Exec(query, types.HistoryCreate, actor, pq.Array(ids)); err != nil {
+		log.Printf("Error recording creation of %d people: %v", len(people), err)
+		return err
+	}
+
+	if len(pending) > 0 {
+		if _, err = tx.Exec("INSERT INTO enrichment_jobs (person_id) SELECT unnest($1::int[])", pq.Array(pending)); err != nil {
+			log.Printf("Error enqueueing enrichment for %d people: %v", len(pending), err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing %d new people: %v", len(people), err)
+		return err
+	}
+
+	log.Printf("Successfully inserted %d people", len(people))
+	return nil
+}
+
+// insertPeopleNationalities сохраняет национальности нескольких записей одним INSERT
+// из массивов, чтобы число параметров не зависело от размера пачки.
+func insertPeopleNationalities(tx *sql.Tx, people []*types.Person) error {
+	var ids, ranks []int64
+	var countries []string
+	var probabilities []float64
+	for _, person := range people {
+		for rank, country := range person.Nationalities {
+			ids = append(ids, int64(person.ID))
+			ranks = append(ranks, int64(rank+1))
+			countries = append(countries, country.CountryID)
+			probabilities = append(probabilities, country.Probability)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec("INSERT INTO people_nationalities (person_id, rank, country_id, probability) SELECT * FROM unnest($1::int[], $2::int[], $3::text[], $4::float8[])",
+		pq.Array(ids), pq.Array(ranks), pq.Array(countries), pq.Array(probabilities))
+	if err != nil {
+		log.Printf("Error while inserting nationalities: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// SQLEnrichmentCache хранит ответы API обогащения в таблице enrichment_cache
+// (реализует enrich.CacheStore).
+type SQLEnrichmentCache struct {
+	DB *sql.DB
+}
+
+// NewSQLEnrichmentCache создает хранилище кэша обогащения.
+func NewSQLEnrichmentCache(db *sql.DB) *SQLEnrichmentCache {
+	return &SQLEnrichmentCache{DB: db}
+}
+
+// GetEnrichment возвращает ответ источника provider для имени name и подсказки
+// страны country, полученный не раньше since, и время его получения; nil, если такого ответа нет.
+func (c *SQLEnrichmentCache) GetEnrichment(ctx context.Context, provider, name, country string, since time.Time) (json.RawMessage, time.Time, error) {
+	var response []byte
+	var fetchedAt time.Time
+	err := c.DB.QueryRowContext(ctx, "SELECT response, fetched_at FROM enrichment_cache WHERE provider = $1 AND name = $2 AND country_id = $3 AND fetched_at > $4",
+		provider, name, country, since).Scan(&response, &fetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return response, fetchedAt, nil
+}
+
+// SaveEnrichment сохраняет ответ источника, заменяя прежний ответ для того же ключа.
+func (c *SQLEnrichmentCache) SaveEnrichment(ctx context.Context, provider, name, country string, response json.RawMessage, fetchedAt time.Time) error {
+	_, err := c.DB.ExecContext(ctx, `INSERT INTO enrichment_cache (provider, name, country_id, response, fetched_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, name, country_id) DO UPDATE SET response = EXCLUDED.response, fetched_at = EXCLUDED.fetched_at`,
+		provider, name, country, []byte(response), fetchedAt)
+	return err
+}
+
+// DeleteExpiredEnrichments удаляет ответы, полученные раньше before, и возвращает их количество.
+func (c *SQLEnrichmentCache) DeleteExpiredEnrichments(before time.Time) (int64, error) {
+	result, err := c.DB.Exec("DELETE FROM enrichment_cache WHERE fetched_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,304 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"junior-test/pkg/types"
+	"log"
+	"strings"
+	"time"
+)
+
+// CreatePendingPerson сохраняет человека со статусом "pending" и ставит задание
+// на обогащение в очередь в той же транзакции.
+func (r *SQLPersonRepository) CreatePendingPerson(person *types.Person, actor string) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	person.EnrichmentStatus = types.EnrichmentPending
+	id, err := insertPerson(tx, person, actor)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT INTO enrichment_jobs (person_id) VALUES ($1)", id)
+	if err != nil {
+		log.Printf("Error enqueueing enrichment for person with ID %d: %v", id, err)
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing a new person: %v", err)
+		return 0, err
+	}
+
+	log.Printf("Successfully inserted a new person with ID %d, enrichment queued", id)
+	return id, nil
+}
+
+// ClaimEnrichmentJob забирает из очереди одно готовое к выполнению задание и
+// блокирует его на время lease. Если заданий нет, возвращает nil.
+// Задание, чей обработчик не уложился в lease, снова станет доступным.
+func (r *SQLPersonRepository) ClaimEnrichmentJob(lease time.Duration) (*types.EnrichmentJob, error) {
+	query := `UPDATE enrichment_jobs SET attempts = attempts + 1, locked_until = now() + $1 * interval '1 second'
+		WHERE id = (
+			SELECT id FROM enrichment_jobs
+			WHERE run_at <= now() AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, person_id, attempts`
+
+	job := &types.EnrichmentJob{}
+	err := r.DB.QueryRow(query, lease.Seconds()).Scan(&job.ID, &job.PersonID, &job.Attempts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Очередь пуста
+		}
+		log.Printf("Error claiming enrichment job: %v", err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// CompleteEnrichmentJob сохраняет результаты обогащения, записывает их в историю
+// и удаляет задание из очереди. Если запись изменилась после того, как ее прочитал
+// воркер, ничего не сохраняется и возвращается ErrVersionMismatch.
+func (r *SQLPersonRepository) CompleteEnrichmentJob(job *types.EnrichmentJob, person *types.Person) error {
+	return r.saveJobEnrichment(job, person, true)
+}
+
+// SaveEnrichmentProgress сохраняет поля, обогащенные при неудачной попытке,
+// чтобы при следующей попытке обогащать только оставшиеся. Задание остается в очереди.
+// Как и CompleteEnrichmentJob, возвращает ErrVersionMismatch, если запись изменилась.
+func (r *SQLPersonRepository) SaveEnrichmentProgress(job *types.EnrichmentJob, person *types.Person) error {
+	return r.saveJobEnrichment(job, person, false)
+}
+
+func (r *SQLPersonRepository) saveJobEnrichment(job *types.EnrichmentJob, person *types.Person, done bool) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPerson(tx, job.PersonID, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err = saveEnrichment(tx, job.PersonID, person, before); err != nil {
+		return err
+	}
+
+	if err = recordHistory(tx, job.PersonID, types.HistoryEnrich, enrichmentActor, before); err != nil {
+		return err
+	}
+
+	if done {
+		if _, err = tx.Exec("DELETE FROM enrichment_jobs WHERE id = $1", job.ID); err != nil {
+			log.Printf("Error deleting enrichment job %d: %v", job.ID, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing enrichment for person with ID %d: %v", job.PersonID, err)
+		return err
+	}
+
+	log.Printf("Enriched person with ID %d, enrichment status %s", job.PersonID, person.EnrichmentStatus)
+	return nil
+}
+
+// SaveEnrichment сохраняет результаты повторного обогащения записи и записывает их
+// в историю от имени actor. Запись должна иметь версию person.Version, иначе
+// возвращается ErrVersionMismatch. Возвращает новую версию записи.
+func (r *SQLPersonRepository) SaveEnrichment(id int, person *types.Person, actor string) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPerson(tx, id, notDeleted)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := saveEnrichment(tx, id, person, before)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = recordHistory(tx, id, types.HistoryEnrich, actor, before); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing enrichment for person with ID %d: %v", id, err)
+		return 0, err
+	}
+
+	log.Printf("Enriched person with ID %d, enrichment status %s", id, person.EnrichmentStatus)
+	return version, nil
+}
+
+// saveEnrichment записывает поля person, обогащенные после снимка before (снимок
+// заблокированной записи id), и статусы обогащения. Поля, обогащенные раньше, и
+// столбцы необогащенных полей не изменяются. Запись изменяется, только если ее версия
+// равна person.Version, иначе возвращается ErrVersionMismatch. Возвращает новую версию записи.
+func saveEnrichment(tx *sql.Tx, id int, person *types.Person, before sql.NullString) (int, error) {
+	var current types.Person
+	if err := json.Unmarshal([]byte(before.String), &current); err != nil {
+		log.Printf("Error decoding snapshot of person with ID %d: %v", id, err)
+		return 0, err
+	}
+	enriched := func(field string) bool {
+		return person.FieldEnriched(field) && !current.FieldEnriched(field)
+	}
+
+	var sets []string
+	args := []interface{}{id, person.Version}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if enriched(types.FieldAge) {
+		set("age", person.Age)
+		set("age_count", person.AgeCount)
+	}
+	if enriched(types.FieldGender) {
+		set("gender", person.Gender)
+		set("gender_probability", person.GenderProbability)
+	}
+	nationality := enriched(types.FieldNationality)
+	if nationality {
+		set("nationality", person.Nationality)
+	}
+	fields, _, _, _ := enrichedValues(person)
+	set("enrichment_status", person.EnrichmentStatus)
+	set("enrichment_fields", fields)
+
+	query := "UPDATE people SET " + strings.Join(sets, ", ") + ", version = version + 1 WHERE id = $1 AND version = $2 RETURNING version"
+	var version int
+	err := tx.QueryRow(query, args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		// Запись изменилась, пока выполнялись запросы к источникам
+		log.Printf("Person with ID %d has version %d, which does not match the enriched one %d", id, current.Version, person.Version)
+		return 0, ErrVersionMismatch
+	}
+	if err != nil {
+		log.Printf("Error saving enrichment for person with ID %d: %v", id, err)
+		return 0, err
+	}
+
+	if !nationality {
+		return version, nil
+	}
+
+	// Список национальностей заменяется только вместе с впервые обогащенной национальностью
+	_, err = tx.Exec("DELETE FROM people_nationalities WHERE person_id = $1", id)
+	if err != nil {
+		log.Printf("Error clearing nationalities for person with ID %d: %v", id, err)
+		return 0, err
+	}
+	if err = insertNationalities(tx, id, person.Nationalities); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// RetryEnrichmentJob откладывает задание до runAt, сохраняя текст ошибки.
+func (r *SQLPersonRepository) RetryEnrichmentJob(job *types.EnrichmentJob, jobErr error, runAt time.Time) error {
+	query := "UPDATE enrichment_jobs SET run_at = $1, locked_until = NULL, last_error = $2 WHERE id = $3"
+	_, err := r.DB.Exec(query, runAt, jobErr.Error(), job.ID)
+	if err != nil {
+		log.Printf("Error rescheduling enrichment job %d: %v", job.ID, err)
+		return err
+	}
+
+	log.Printf("Enrichment job %d for person with ID %d rescheduled to %s", job.ID, job.PersonID, runAt.Format(time.RFC3339))
+	return nil
+}
+
+// PostponeEnrichmentJob откладывает задание до runAt, не засчитывая попытку:
+// обогащение не выполнялось, например, потому что квота внешних API исчерпана.
+func (r *SQLPersonRepository) PostponeEnrichmentJob(job *types.EnrichmentJob, jobErr error, runAt time.Time) error {
+	query := "UPDATE enrichment_jobs SET run_at = $1, locked_until = NULL, last_error = $2, attempts = GREATEST(attempts - 1, 0) WHERE id = $3"
+	_, err := r.DB.Exec(query, runAt, jobErr.Error(), job.ID)
+	if err != nil {
+		log.Printf("Error postponing enrichment job %d: %v", job.ID, err)
+		return err
+	}
+
+	log.Printf("Enrichment job %d for person with ID %d postponed to %s", job.ID, job.PersonID, runAt.Format(time.RFC3339))
+	return nil
+}
+
+// FailEnrichmentJob помечает необогащенные поля как неудачные и удаляет задание из очереди.
+// Запись получает статус partial, если часть полей обогащена, иначе - failed.
+func (r *SQLPersonRepository) FailEnrichmentJob(job *types.EnrichmentJob, jobErr error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPerson(tx, job.PersonID, "")
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	query := `UPDATE people SET
+		enrichment_fields = COALESCE((SELECT jsonb_object_agg(key, CASE WHEN value = $1 THEN $2 ELSE value END)
+			FROM jsonb_each_text(enrichment_fields)), '{}'),
+		enrichment_status = CASE WHEN enrichment_fields @> jsonb_build_object($3::text, $4::text)
+			OR enrichment_fields @> jsonb_build_object($5::text, $4::text)
+			OR enrichment_fields @> jsonb_build_object($6::text, $4::text) THEN $7 ELSE $2 END,
+		version = version + 1
+		WHERE id = $8`
+	_, err = tx.Exec(query, types.EnrichmentPending, types.EnrichmentFailed, types.FieldAge, types.EnrichmentComplete,
+		types.FieldGender, types.FieldNationality, types.EnrichmentPartial, job.PersonID)
+	if err != nil {
+		log.Printf("Error marking enrichment failed for person with ID %d: %v", job.PersonID, err)
+		return err
+	}
+
+	if err = recordHistory(tx, job.PersonID, types.HistoryEnrich, enrichmentActor, before); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM enrichment_jobs WHERE id = $1", job.ID); err != nil {
+		log.Printf("Error deleting enrichment job %d: %v", job.ID, err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing failed enrichment for person with ID %d: %v", job.PersonID, err)
+		return err
+	}
+
+	log.Printf("Enrichment for person with ID %d failed after %d attempts: %v", job.PersonID, job.Attempts, jobErr)
+	return nil
+}
+
+// DeleteEnrichmentJob удаляет задание из очереди без изменения записи о человеке.
+func (r *SQLPersonRepository) DeleteEnrichmentJob(job *types.EnrichmentJob) error {
+	if _, err := r.DB.Exec("DELETE FROM enrichment_jobs WHERE id = $1", job.ID); err != nil {
+		log.Printf("Error deleting enrichment job %d: %v", job.ID, err)
+		return err
+	}
+	return nil
+}
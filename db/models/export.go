@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"junior-test/pkg/types"
+	"log"
+)
+
+// exportFetchSize - количество записей, читаемых из курсора за один FETCH.
+const exportFetchSize = 1000
+
+// ExportPeople передает в fn все записи, подходящие под filter, в порядке его сортировки.
+// Пагинация фильтра не учитывается. Записи читаются через серверный курсор пачками
+// по exportFetchSize, поэтому в памяти одновременно находится не больше одной пачки.
+// Ошибка fn прекращает чтение и возвращается вызывающему.
+func (r *SQLPersonRepository) ExportPeople(ctx context.Context, filter types.PersonFilter, fn func(*types.Person) error) error {
+	filter.Page, filter.PageSize, filter.Cursor, filter.After = 0, 0, nil, nil
+
+	query, args, fuzzy, err := selectPeople(filter)
+	if err != nil {
+		return err
+	}
+
+	// Курсор существует только внутри транзакции; REPEATABLE READ дает согласованный
+	// снимок данных на все время выгрузки
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Printf("Error starting export transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	log.Printf("Exporting people: %s, args: %v", query, args)
+
+	if _, err := tx.ExecContext(ctx, "DECLARE people_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		log.Printf("Error declaring export cursor: %v", err)
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM people_export", exportFetchSize)
+	exported := 0
+	for {
+		fetched, err := fetchPeople(ctx, tx, fetch, fuzzy, fn)
+		exported += fetched
+		if err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			break
+		}
+	}
+
+	log.Printf("Exported %d people", exported)
+	return tx.Commit()
+}
+
+// fetchPeople выполняет один FETCH из курсора выгрузки и передает записи в fn.
+// Возвращает количество прочитанных записей.
+func fetchPeople(ctx context.Context, tx *sql.Tx, fetch string, fuzzy bool, fn func(*types.Person) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		log.Printf("Error fetching people for export: %v", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var p types.Person
+		var extra []interface{}
+		if fuzzy {
+			extra = append(extra, &p.Score)
+		}
+		if err := scanPerson(rows, &p, extra...); err != nil {
+			log.Printf("Error scanning person: %v", err)
+			return fetched, err
+		}
+		fetched++
+		if err := fn(&p); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, rows.Err()
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql"
+	"junior-test/pkg/types"
+	"log"
+)
+
+// enrichmentActor - автор изменений, внесенных пулом воркеров обогащения.
+const enrichmentActor = "enrichment-worker"
+
+// purgeActor - автор окончательного удаления записей по истечении срока хранения.
+const purgeActor = "purge-worker"
+
+// personSnapshot - SQL-выражение со снимком записи people для истории изменений.
+const personSnapshot = `jsonb_build_object(
+	'name', name, 'surname', surname, 'patronymic', patronymic,
+	'age', age, 'age_count', age_count, 'gender', gender, 'gender_probability', gender_probability,
+	'nationality', nationality, 'enrichment_status', enrichment_status, 'enrichment_fields', enrichment_fields,
+	'nationalities', (SELECT jsonb_agg(jsonb_build_object('country_id', country_id, 'probability', probability) ORDER BY rank)
+		FROM people_nationalities WHERE person_id = people.id),
+	'version', version, 'deleted_at', deleted_at)`
+
+// snapshotPerson блокирует запись до конца транзакции и возвращает ее снимок.
+// Если задано условие scope (например, notDeleted), запись должна ему удовлетворять;
+// sql.ErrNoRows означает, что подходящей записи нет.
+func snapshotPerson(tx *sql.Tx, id int, scope string) (sql.NullString, error) {
+	query := "SELECT " + personSnapshot + " FROM people WHERE id = $1"
+	if scope != "" {
+		query += " AND " + scope
+	}
+	var snapshot sql.NullString
+	err := tx.QueryRow(query+" FOR UPDATE", id).Scan(&snapshot)
+	if err != nil {
+		log.Printf("Error reading snapshot of person with ID %d: %v", id, err)
+	}
+	return snapshot, err
+}
+
+// recordHistory сохраняет запись истории в транзакции изменения. Снимок "после"
+// берется из текущего состояния записи, before - снимок до изменения (NULL при создании).
+func recordHistory(tx *sql.Tx, id int, action, actor string, before sql.NullString) error {
+	query := "INSERT INTO people_history (person_id, action, actor, before, after) SELECT id, $2, $3, $4, " + personSnapshot + " FROM people WHERE id = $1"
+	if _, err := tx.Exec(query, id, action, actor, before); err != nil {
+		log.Printf("Error recording %s of person with ID %d: %v", action, id, err)
+		return err
+	}
+	return nil
+}
+
+// GetPersonHistory возвращает страницу истории изменений записи (сначала новые)
+// и общее количество записей истории.
+func (r *SQLPersonRepository) GetPersonHistory(id, page, pageSize int) ([]*types.PersonHistory, int, error) {
+	var total int
+	if err := r.DB.QueryRow("SELECT COUNT(*) FROM people_history WHERE person_id = $1", id).Scan(&total); err != nil {
+		log.Printf("Error counting history of person with ID %d: %v", id, err)
+		return nil, 0, err
+	}
+
+	query := "SELECT id, person_id, action, actor, before, after, created_at FROM people_history WHERE person_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3"
+	rows, err := r.DB.Query(query, id, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("Error retrieving history of person with ID %d: %v", id, err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var history []*types.PersonHistory
+	for rows.Next() {
+		h := &types.PersonHistory{}
+		if err := rows.Scan(&h.ID, &h.PersonID, &h.Action, &h.Actor, (*[]byte)(&h.Before), (*[]byte)(&h.After), &h.CreatedAt); err != nil {
+			log.Printf("Error scanning history: %v", err)
+			return nil, 0, err
+		}
+		history = append(history, h)
+	}
+
+	return history, total, rows.Err()
+}
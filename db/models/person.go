@@ -2,50 +2,165 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"junior-test/pkg/translit"
 	"junior-test/pkg/types"
 	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// PersonRepository - операции с записями о людях. versions - допустимые версии
+// записи из If-Match (nil - без проверки), actor - автор изменения для истории.
+// FilterListPeople выбирает страницу по номеру или по курсору из filter.
 type PersonRepository interface {
-	CreatePerson(person *types.Person) (int, error)
+	CreatePerson(person *types.Person, actor string) (int, error)
 	GetPersonByID(id int) (*types.Person, error)
-	UpdatePerson(id int, person *types.Person) error
-	DeletePerson(id int) error
-	ListPeople(filter types.PersonFilter) ([]*types.Person, error)
+	UpdatePerson(id int, person *types.Person, versions []int, actor string) (int, error)
+	PatchPerson(id int, changes map[string]interface{}, versions []int, actor string) (int, error)
+	DeletePerson(id int, versions []int, actor string) error
+	FilterListPeople(filter types.PersonFilter) ([]*types.Person, error)
+	CountPeople(filter types.PersonFilter) (int, error)
 }
 
+var _ PersonRepository = (*SQLPersonRepository)(nil)
+
 // SQLPersonRepository представляет реализацию интерфейса PersonRepository для работы с PostgreSQL.
 type SQLPersonRepository struct {
 	DB *sql.DB
 }
 
+// personColumns - столбцы таблицы people в порядке, ожидаемом scanPerson.
+// Пока запись не обогащена, age, gender и nationality равны NULL и возвращаются как 0 и "".
+const personColumns = "id, name, surname, COALESCE(patronymic, ''), COALESCE(age, 0), age_count, COALESCE(gender, ''), gender_probability, COALESCE(nationality, ''), enrichment_status, created_at, updated_at, version, deleted_at, enrichment_fields"
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPerson считывает строку, выбранную по personColumns,
+// и дополнительные столбцы, следующие за ними, в extra.
+func scanPerson(row rowScanner, p *types.Person, extra ...interface{}) error {
+	var fields []byte
+	dest := []interface{}{&p.ID, &p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.AgeCount, &p.Gender, &p.GenderProbability, &p.Nationality, &p.EnrichmentStatus, &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.DeletedAt, &fields}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields, &p.EnrichmentFields)
+}
+
 // NewSQLPersonRepository создает новый экземпляр SQLPersonRepository.
 func NewSQLPersonRepository(db *sql.DB) *SQLPersonRepository {
 	return &SQLPersonRepository{DB: db}
 }
 
 // Реализация методов интерфейса PersonRepository
-func (r *SQLPersonRepository) CreatePerson(person *types.Person) (int, error) {
-	query := "INSERT INTO people (name, surname, patronymic, age, gender, nationality) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+func (r *SQLPersonRepository) CreatePerson(person *types.Person, actor string) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertPerson(tx, person, actor)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing a new person: %v", err)
+		return 0, err
+	}
+
+	log.Printf("Successfully inserted a new person with ID %d", id)
+	return id, nil
+}
+
+// insertPerson добавляет запись о человеке, список его национальностей
+// и запись истории о создании в рамках транзакции.
+func insertPerson(tx *sql.Tx, person *types.Person, actor string) (int, error) {
+	fields, age, gender, nationality := enrichedValues(person)
+
+	query := "INSERT INTO people (name, surname, patronymic, age, age_count, gender, gender_probability, nationality, enrichment_status, enrichment_fields, search_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id"
 	var id int
-	err := r.DB.QueryRow(query, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nationality).Scan(&id)
+	err := tx.QueryRow(query, person.Name, person.Surname, person.Patronymic, age, person.AgeCount, gender, person.GenderProbability, nationality, person.EnrichmentStatus, fields, searchKey(person)).Scan(&id)
 	if err != nil {
 		log.Printf("Error while inserting a new person: %v", err)
 		return 0, err
 	}
 
-	log.Printf("Successfully inserted a new person with ID %d", id)
+	if err = insertNationalities(tx, id, person.Nationalities); err != nil {
+		return 0, err
+	}
+
+	if err = recordHistory(tx, id, types.HistoryCreate, actor, sql.NullString{}); err != nil {
+		return 0, err
+	}
+
+	person.ID = id
 	return id, nil
 }
 
+// enrichedValues вычисляет статус обогащения записи (по умолчанию complete) и возвращает
+// статусы полей в JSON и значения обогащаемых столбцов: пока поле не обогащено, оно неизвестно (NULL).
+func enrichedValues(person *types.Person) (fields []byte, age, gender, nationality interface{}) {
+	person.UpdateEnrichmentStatus()
+	fields, _ = json.Marshal(person.EnrichmentFields)
+
+	if person.FieldEnriched(types.FieldAge) {
+		age = person.Age
+	}
+	if person.FieldEnriched(types.FieldGender) {
+		gender = person.Gender
+	}
+	if person.FieldEnriched(types.FieldNationality) {
+		nationality = person.Nationality
+	}
+	return fields, age, gender, nationality
+}
+
+// insertNationalities сохраняет полный список национальностей в порядке убывания вероятности.
+func insertNationalities(tx *sql.Tx, id int, nationalities []types.Nationality) error {
+	for rank, country := range nationalities {
+		_, err := tx.Exec("INSERT INTO people_nationalities (person_id, rank, country_id, probability) VALUES ($1, $2, $3, $4)", id, rank+1, country.CountryID, country.Probability)
+		if err != nil {
+			log.Printf("Error while inserting nationalities for person with ID %d: %v", id, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// notDeleted - условие, исключающее удаленные записи. Удаленные записи не видны
+// при чтении и изменении, пока их не восстановят.
+const notDeleted = "deleted_at IS NULL"
+
+// GetPersonByID возвращает запись, если она существует и не удалена, иначе nil.
 func (r *SQLPersonRepository) GetPersonByID(id int) (*types.Person, error) {
-	query := "SELECT name, surname, patronymic, age, gender, nationality FROM people WHERE id = $1"
+	return r.getPerson(id, false)
+}
+
+// GetPersonIncludingDeleted возвращает запись, даже если она удалена, но еще не удалена окончательно.
+func (r *SQLPersonRepository) GetPersonIncludingDeleted(id int) (*types.Person, error) {
+	return r.getPerson(id, true)
+}
+
+func (r *SQLPersonRepository) getPerson(id int, includeDeleted bool) (*types.Person, error) {
+	query := "SELECT " + personColumns + " FROM people WHERE id = $1"
+	if !includeDeleted {
+		query += " AND " + notDeleted
+	}
 
 	row := r.DB.QueryRow(query, id)
 
 	person := &types.Person{}
-	err := row.Scan(&person.Name, &person.Surname, &person.Patronymic, &person.Age, &person.Gender, &person.Nationality)
+	err := scanPerson(row, person)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Person with ID %d not found", id)
@@ -55,144 +170,336 @@ func (r *SQLPersonRepository) GetPersonByID(id int) (*types.Person, error) {
 		return nil, err // Возникла ошибка при выполнении запроса
 	}
 
+	person.Nationalities, err = r.getNationalities(id)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Printf("Retrieved person with ID %d", id)
 	return person, nil
 }
 
-func (r *SQLPersonRepository) UpdatePerson(id int, person *types.Person) error {
-
-	var count int
-	err := r.DB.QueryRow("SELECT COUNT(*) FROM people WHERE id = $1", id).Scan(&count)
+// getNationalities возвращает список вероятных национальностей человека по убыванию вероятности.
+func (r *SQLPersonRepository) getNationalities(id int) ([]types.Nationality, error) {
+	rows, err := r.DB.Query("SELECT country_id, probability FROM people_nationalities WHERE person_id = $1 ORDER BY rank", id)
 	if err != nil {
-		log.Printf("Error checking existence of person with ID %d: %v", id, err)
-		return err
+		log.Printf("Error retrieving nationalities for person with ID %d: %v", id, err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	if count == 0 {
-		// Записи с указанным ID не существует
-		log.Printf("Person with ID %d not found", id)
-		return fmt.Errorf("Person with ID %d not found", id)
+	var nationalities []types.Nationality
+	for rows.Next() {
+		var n types.Nationality
+		if err := rows.Scan(&n.CountryID, &n.Probability); err != nil {
+			log.Printf("Error scanning nationality: %v", err)
+			return nil, err
+		}
+		nationalities = append(nationalities, n)
 	}
 
-	// Собираем SQL-запрос динамически на основе измененных полей
-	query := "UPDATE people SET"
-	var params []interface{}
-	paramCount := 1
+	return nationalities, rows.Err()
+}
+
+// ErrVersionMismatch возвращается, если версия записи не совпала ни с одной из
+// ожидаемых (заголовок If-Match): запись успели изменить.
+var ErrVersionMismatch = errors.New("person version mismatch")
+
+// containsVersion сообщает, входит ли version в список ожидаемых версий.
+func containsVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdatePerson обновляет только непустые поля person (пустые строки и нулевой
+// возраст пропускаются). Используется устаревшим маршрутом /update/:id.
+func (r *SQLPersonRepository) UpdatePerson(id int, person *types.Person, versions []int, actor string) (int, error) {
+	changes := map[string]interface{}{}
 
 	if person.Name != "" {
-		query += fmt.Sprintf(" name = $%d,", paramCount)
-		params = append(params, person.Name)
-		paramCount++
+		changes["name"] = person.Name
 	}
 	if person.Surname != "" {
-		query += fmt.Sprintf(" surname = $%d,", paramCount)
-		params = append(params, person.Surname)
-		paramCount++
+		changes["surname"] = person.Surname
 	}
 	if person.Patronymic != "" {
-		query += fmt.Sprintf(" patronymic = $%d,", paramCount)
-		params = append(params, person.Patronymic)
-		paramCount++
+		changes["patronymic"] = person.Patronymic
 	}
 	if person.Age != 0 {
-		query += fmt.Sprintf(" age = $%d,", paramCount)
-		params = append(params, person.Age)
-		paramCount++
+		changes["age"] = person.Age
 	}
 	if person.Gender != "" {
-		query += fmt.Sprintf(" gender = $%d,", paramCount)
-		params = append(params, person.Gender)
-		paramCount++
+		changes["gender"] = person.Gender
 	}
 	if person.Nationality != "" {
-		query += fmt.Sprintf(" nationality = $%d,", paramCount)
-		params = append(params, person.Nationality)
-		paramCount++
+		changes["nationality"] = person.Nationality
+	}
+
+	return r.PatchPerson(id, changes, versions, actor)
+}
+
+// EditableColumns - столбцы people, которые можно изменять через API, в порядке обновления.
+var EditableColumns = []string{"name", "surname", "patronymic", "age", "gender", "nationality"}
+
+// GetPersonDocument возвращает изменяемые поля записи в виде JSON-документа
+// (NULL - nil, возраст - float64, как после json.Unmarshal) и версию записи.
+// Если записи нет, возвращает nil.
+func (r *SQLPersonRepository) GetPersonDocument(id int) (map[string]interface{}, int, error) {
+	var name, surname string
+	var patronymic, gender, nationality sql.NullString
+	var age sql.NullInt64
+	var version int
+
+	query := "SELECT name, surname, patronymic, age, gender, nationality, version FROM people WHERE id = $1 AND " + notDeleted
+	err := r.DB.QueryRow(query, id).Scan(&name, &surname, &patronymic, &age, &gender, &nationality, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Person with ID %d not found", id)
+			return nil, 0, nil
+		}
+		log.Printf("Error retrieving person with ID %d: %v", id, err)
+		return nil, 0, err
+	}
+
+	doc := map[string]interface{}{"name": name, "surname": surname}
+	if patronymic.Valid && patronymic.String != "" {
+		doc["patronymic"] = patronymic.String
+	}
+	if age.Valid {
+		doc["age"] = float64(age.Int64)
+	}
+	if gender.Valid && gender.String != "" {
+		doc["gender"] = gender.String
+	}
+	if nationality.Valid && nationality.String != "" {
+		doc["nationality"] = nationality.String
 	}
 
-	// Удаляем последнюю запятую, если она есть
-	if len(query) > 0 && query[len(query)-1] == ',' {
-		query = query[:len(query)-1]
+	return doc, version, nil
+}
+
+// PatchPerson записывает изменения полей из EditableColumns; значение nil очищает поле.
+// Измененные возраст, пол и национальность получают статус обогащения complete,
+// очищенные - failed; статус записи пересчитывается в том же UPDATE.
+// Если versions не nil, запись изменяется только при совпадении ее версии с одной
+// из versions, иначе возвращается ErrVersionMismatch. Изменение записывается
+// в историю от имени actor. Возвращает новую версию записи или sql.ErrNoRows, если записи нет.
+func (r *SQLPersonRepository) PatchPerson(id int, changes map[string]interface{}, versions []int, actor string) (int, error) {
+	version, err := r.execVersioned(id, versions, types.HistoryUpdate, actor, notDeleted, func(current *types.Person) ([]string, []interface{}) {
+		// Собираем SET динамически на основе измененных полей: $1 - ID, $2 - версии, далее значения
+		var sets []string
+		var params []interface{}
+		for _, column := range EditableColumns {
+			value, ok := changes[column]
+			if !ok {
+				continue
+			}
+			sets = append(sets, fmt.Sprintf("%s = $%d", column, len(params)+3))
+			params = append(params, value)
+		}
+
+		// ФИО могло измениться - ключ нечеткого поиска пересчитывается в том же UPDATE
+		names := map[string]*string{"name": &current.Name, "surname": &current.Surname, "patronymic": &current.Patronymic}
+		changed := false
+		for column, field := range names {
+			if value, ok := changes[column]; ok {
+				*field, _ = value.(string)
+				changed = true
+			}
+		}
+		if changed {
+			sets = append(sets, fmt.Sprintf("search_key = $%d", len(params)+3))
+			params = append(params, searchKey(current))
+		}
+
+		// Заданное вручную поле считается обогащенным, чтобы воркер и /enrich его
+		// не перезаписали; очищенное поле снова можно обогатить
+		edited := false
+		for _, field := range types.EnrichedFields {
+			value, ok := changes[field]
+			if !ok {
+				continue
+			}
+			status := types.EnrichmentComplete
+			if text, isText := value.(string); value == nil || isText && text == "" {
+				status = types.EnrichmentFailed
+			}
+			current.SetFieldStatus(field, status)
+			edited = true
+		}
+		if edited {
+			current.UpdateEnrichmentStatus()
+			fields, _ := json.Marshal(current.EnrichmentFields)
+			sets = append(sets, fmt.Sprintf("enrichment_fields = $%d", len(params)+3), fmt.Sprintf("enrichment_status = $%d", len(params)+4))
+			params = append(params, fields, current.EnrichmentStatus)
+		}
+		return sets, params
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	// Завершаем запрос
-	query += fmt.Sprintf(" WHERE id = $%d", paramCount)
-	params = append(params, id)
+	log.Printf("Updated person with ID %d", id)
+	return version, nil
+}
 
-	// Выполняем SQL-запрос
-	_, err = r.DB.Exec(query, params...)
+// DeletePerson помечает запись удаленной; окончательно она удаляется
+// PurgeDeletedPeople или PurgePerson. Версия проверяется так же, как в PatchPerson.
+func (r *SQLPersonRepository) DeletePerson(id int, versions []int, actor string) error {
+	_, err := r.execVersioned(id, versions, types.HistoryDelete, actor, notDeleted, setColumns("deleted_at = now()"))
 	if err != nil {
-		log.Printf("Error updating person with ID %d: %v", id, err)
 		return err
 	}
 
-	log.Printf("Updated person with ID %d", id)
+	log.Printf("Deleted person with ID %d", id)
 	return nil
 }
 
-func (r *SQLPersonRepository) DeletePerson(id int) error {
-	query := "DELETE FROM people WHERE id = $1"
+// RestorePerson восстанавливает удаленную запись и возвращает ее новую версию.
+// Возвращает sql.ErrNoRows, если удаленной записи с таким ID нет.
+func (r *SQLPersonRepository) RestorePerson(id int, versions []int, actor string) (int, error) {
+	version, err := r.execVersioned(id, versions, types.HistoryRestore, actor, "deleted_at IS NOT NULL", setColumns("deleted_at = NULL"))
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Restored person with ID %d", id)
+	return version, nil
+}
 
-	result, err := r.DB.Exec(query, id)
+// PurgePerson окончательно удаляет ранее удаленную запись, не дожидаясь
+// окончания срока хранения. Возвращает sql.ErrNoRows, если удаленной записи нет.
+// История записи сохраняется и дополняется действием purge от имени actor.
+func (r *SQLPersonRepository) PurgePerson(id int, actor string) error {
+	purged, err := r.purge("id = $1 AND deleted_at IS NOT NULL", id, actor)
 	if err != nil {
-		log.Printf("Error deleting person with ID %d: %v", id, err)
+		log.Printf("Error purging person with ID %d: %v", id, err)
 		return err
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		log.Printf("Not ID %d", id)
-		return sql.ErrNoRows // Возвращаем ошибку sql.ErrNoRows, если ни одна запись не была удалена
+	if purged == 0 {
+		log.Printf("Deleted person with ID %d not found", id)
+		return sql.ErrNoRows
 	}
 
-	log.Printf("Deleted person with ID %d", id)
+	log.Printf("Purged person with ID %d", id)
 	return nil
 }
 
-func (r *SQLPersonRepository) FilterListPeople(filter types.PersonFilter) ([]*types.Person, error) {
-	query := "SELECT name, surname, patronymic, age, gender, nationality FROM people WHERE 1=1"
-	var args []interface{}
-	paramCount := 1
+// PurgeDeletedPeople окончательно удаляет записи, удаленные раньше чем retention назад,
+// и возвращает их количество. Национальности и задания удаляются каскадно.
+func (r *SQLPersonRepository) PurgeDeletedPeople(retention time.Duration) (int64, error) {
+	purged, err := r.purge("deleted_at < now() - $1 * interval '1 second'", retention.Seconds(), purgeActor)
+	if err != nil {
+		log.Printf("Error purging deleted people: %v", err)
+		return 0, err
+	}
 
-	if filter.Name != "" {
-		query += fmt.Sprintf(" AND LOWER(name) = LOWER($%d)", paramCount)
-		args = append(args, filter.Name)
-		paramCount++
+	if purged > 0 {
+		log.Printf("Purged %d deleted people", purged)
 	}
-	if filter.Surname != "" {
-		query += fmt.Sprintf(" AND LOWER(surname) = LOWER($%d)", paramCount)
-		args = append(args, filter.Surname)
-		paramCount++
+	return purged, nil
+}
+
+// purge удаляет записи, удовлетворяющие условию where с параметром $1, и в том же
+// запросе записывает в историю их последние снимки, чтобы история пережила удаление.
+func (r *SQLPersonRepository) purge(where string, param interface{}, actor string) (int64, error) {
+	query := "WITH purged AS (DELETE FROM people WHERE " + where + " RETURNING id, " + personSnapshot + " AS snapshot) " +
+		"INSERT INTO people_history (person_id, action, actor, before) SELECT id, $2, $3, snapshot FROM purged"
+	result, err := r.DB.Exec(query, param, types.HistoryPurge, actor)
+	if err != nil {
+		return 0, err
 	}
-	if filter.Patronymic != "" {
-		query += fmt.Sprintf(" AND LOWER(patronymic) = LOWER($%d)", paramCount)
-		args = append(args, filter.Patronymic)
-		paramCount++
+	return result.RowsAffected()
+}
+
+// execVersioned изменяет запись id, удовлетворяющую условию scope, в одной транзакции.
+// Запись блокируется до конца транзакции (sql.ErrNoRows, если ее нет), затем change
+// по ее текущему состоянию возвращает выражения SET с параметрами начиная с $3.
+// Версия проверяется в условии самого UPDATE: если versions не nil и версия записи
+// не входит в них, запись не изменяется и возвращается ErrVersionMismatch.
+// Если change не вернул выражений, только проверяется версия и история не пишется.
+// Изменение записывается в историю как action от имени actor в той же транзакции.
+func (r *SQLPersonRepository) execVersioned(id int, versions []int, action, actor, scope string, change func(current *types.Person) ([]string, []interface{})) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, err
 	}
-	if filter.MinAge != 0 {
-		query += fmt.Sprintf(" AND age >= $%d", paramCount)
-		args = append(args, filter.MinAge)
-		paramCount++
+	defer tx.Rollback()
+
+	var before sql.NullString
+	err = tx.QueryRow("SELECT "+personSnapshot+" FROM people WHERE id = $1 AND "+scope+" FOR UPDATE", id).Scan(&before)
+	if err == sql.ErrNoRows {
+		log.Printf("Person with ID %d not found", id)
+		return 0, sql.ErrNoRows // Записи с указанным ID нет
 	}
-	if filter.MaxAge != 0 {
-		query += fmt.Sprintf(" AND age <= $%d", paramCount)
-		args = append(args, filter.MaxAge)
-		paramCount++
+	if err != nil {
+		log.Printf("Error locking person with ID %d: %v", id, err)
+		return 0, err
 	}
-	if filter.Gender != "" {
-		query += fmt.Sprintf(" AND gender = $%d", paramCount)
-		args = append(args, filter.Gender)
-		paramCount++
+
+	var current types.Person
+	if err = json.Unmarshal([]byte(before.String), &current); err != nil {
+		log.Printf("Error decoding snapshot of person with ID %d: %v", id, err)
+		return 0, err
 	}
-	if filter.Nationality != "" {
-		query += fmt.Sprintf(" AND LOWER(nationality) = LOWER($%d)", paramCount)
-		args = append(args, filter.Nationality)
-		paramCount++
+
+	sets, params := change(&current)
+	if len(sets) == 0 {
+		if versions != nil && !containsVersion(versions, current.Version) {
+			log.Printf("Person with ID %d has version %d, which does not match the expected one", id, current.Version)
+			return 0, ErrVersionMismatch
+		}
+		return current.Version, nil
 	}
 
-	if filter.Page > 0 && filter.PageSize > 0 {
-		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", paramCount, (paramCount + 1))
-		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	// Без versions подходит любая версия, то есть заблокированная
+	expected := versions
+	if expected == nil {
+		expected = []int{current.Version}
+	}
+
+	query := "UPDATE people SET " + strings.Join(sets, ", ") + ", version = version + 1 WHERE id = $1 AND version = ANY($2) RETURNING version"
+	var version int
+	err = tx.QueryRow(query, append([]interface{}{id, pq.Array(expected)}, params...)...).Scan(&version)
+	if err == sql.ErrNoRows {
+		// Запись заблокирована и существует, значит, не совпала версия
+		log.Printf("Person with ID %d has version %d, which does not match the expected one", id, current.Version)
+		return 0, ErrVersionMismatch
+	}
+	if err != nil {
+		log.Printf("Error modifying person with ID %d: %v", id, err)
+		return 0, err
+	}
+
+	if err = recordHistory(tx, id, action, actor, before); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing changes of person with ID %d: %v", id, err)
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// setColumns возвращает для execVersioned изменение без параметров.
+func setColumns(sets ...string) func(*types.Person) ([]string, []interface{}) {
+	return func(*types.Person) ([]string, []interface{}) {
+		return sets, nil
+	}
+}
+
+func (r *SQLPersonRepository) FilterListPeople(filter types.PersonFilter) ([]*types.Person, error) {
+	query, args, fuzzy, err := selectPeople(filter)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("Executing query: %s, args: %v", query, args)
@@ -207,7 +514,11 @@ func (r *SQLPersonRepository) FilterListPeople(filter types.PersonFilter) ([]*ty
 	var people []*types.Person
 	for rows.Next() {
 		var p types.Person
-		err := rows.Scan(&p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.Gender, &p.Nationality)
+		var extra []interface{}
+		if fuzzy {
+			extra = append(extra, &p.Score)
+		}
+		err := scanPerson(rows, &p, extra...)
 		if err != nil {
 			log.Printf("Error scanning person: %v", err)
 			return nil, err
@@ -224,3 +535,180 @@ func (r *SQLPersonRepository) FilterListPeople(filter types.PersonFilter) ([]*ty
 
 	return people, nil
 }
+
+// selectPeople строит запрос списка людей по фильтру: условия, сортировку и пагинацию
+// (если заданы Page и PageSize или Cursor). fuzzy означает, что за столбцами
+// personColumns следует степень сходства score.
+func selectPeople(filter types.PersonFilter) (query string, args []interface{}, fuzzy bool, err error) {
+	order, err := ParseSort(filter.Sort)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	where, args := peopleWhere(filter)
+	paramCount := len(args) + 1
+
+	// При нечетком поиске возвращаем степень сходства каждой записи с запросом
+	columns := personColumns
+	fuzzy = filter.Search == SearchFuzzy
+	if fuzzy {
+		columns += fmt.Sprintf(", word_similarity($%d, search_key) AS score", paramCount)
+		args = append(args, translit.Normalize(filter.Q))
+		paramCount++
+	}
+	query = "SELECT " + columns + " FROM people" + where
+
+	// Keyset-пагинация: записи строго после позиции курсора
+	if filter.Cursor != nil && filter.After != nil {
+		condition, values, err := keysetCondition(order, filter.After, paramCount)
+		if err != nil {
+			return "", nil, false, err
+		}
+		query += condition
+		args = append(args, values...)
+		paramCount += len(values)
+	}
+
+	if filter.Cursor != nil && RelevanceOrder(filter) {
+		return "", nil, false, ErrCursorRelevance
+	}
+
+	if fuzzy && filter.Sort == "" {
+		// При нечетком поиске без явной сортировки наиболее похожие записи идут первыми
+		query += " ORDER BY score DESC, id"
+	} else if RelevanceOrder(filter) {
+		// При полнотекстовом поиске без явной сортировки наиболее релевантные записи идут первыми
+		query += fmt.Sprintf(" ORDER BY ts_rank(search_vector, to_tsquery('simple', $%d)) DESC, id", paramCount)
+		args = append(args, SearchQuery(filter.Q))
+		paramCount++
+	} else {
+		query += orderByClause(order)
+	}
+
+	if filter.Cursor != nil {
+		if filter.PageSize > 0 {
+			query += fmt.Sprintf(" LIMIT $%d", paramCount)
+			args = append(args, filter.PageSize)
+		}
+	} else if filter.Page > 0 && filter.PageSize > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", paramCount, (paramCount + 1))
+		args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	}
+
+	return query, args, fuzzy, nil
+}
+
+// CountPeople возвращает количество записей, удовлетворяющих фильтру (без учета пагинации).
+func (r *SQLPersonRepository) CountPeople(filter types.PersonFilter) (int, error) {
+	where, args := peopleWhere(filter)
+
+	var total int
+	err := r.DB.QueryRow("SELECT COUNT(*) FROM people"+where, args...).Scan(&total)
+	if err != nil {
+		log.Printf("Error counting people: %v", err)
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// peopleWhere строит условие WHERE по фильтру и возвращает его вместе с аргументами запроса.
+func peopleWhere(filter types.PersonFilter) (string, []interface{}) {
+	query := " WHERE 1=1"
+	if !filter.IncludeDeleted {
+		query += " AND " + notDeleted
+	}
+	var args []interface{}
+	paramCount := 1
+
+	names := []struct{ column, value string }{
+		{"name", filter.Name},
+		{"surname", filter.Surname},
+		{"patronymic", filter.Patronymic},
+	}
+	for _, n := range names {
+		if n.value == "" {
+			continue
+		}
+		condition, arg := nameCondition(n.column, n.value, filter.Match, paramCount)
+		query += condition
+		args = append(args, arg)
+		paramCount++
+	}
+	if filter.Search == SearchFuzzy {
+		if key := translit.Normalize(filter.Q); key != "" {
+			query += fmt.Sprintf(" AND $%d <%% search_key", paramCount)
+			args = append(args, key)
+			paramCount++
+		}
+	} else if search := SearchQuery(filter.Q); search != "" {
+		query += fmt.Sprintf(" AND search_vector @@ to_tsquery('simple', $%d)", paramCount)
+		args = append(args, search)
+		paramCount++
+	}
+	if filter.MinAge != nil {
+		query += fmt.Sprintf(" AND age >= $%d", paramCount)
+		args = append(args, *filter.MinAge)
+		paramCount++
+	}
+	if filter.MaxAge != nil {
+		query += fmt.Sprintf(" AND age <= $%d", paramCount)
+		args = append(args, *filter.MaxAge)
+		paramCount++
+	}
+
+	lists := []struct {
+		column string
+		values types.StringList
+		negate bool
+	}{
+		{"gender", filter.Gender, false},
+		{"nationality", filter.Nationality, false},
+		{"gender", filter.NotGender, true},
+		{"nationality", filter.NotNationality, true},
+	}
+	for _, l := range lists {
+		if len(l.values) == 0 {
+			continue
+		}
+		if l.negate {
+			query += fmt.Sprintf(" AND (%s IS NULL OR LOWER(%s) <> ALL($%d))", l.column, l.column, paramCount)
+		} else {
+			query += fmt.Sprintf(" AND LOWER(%s) = ANY($%d)", l.column, paramCount)
+		}
+		args = append(args, pq.Array(lowerAll(l.values)))
+		paramCount++
+	}
+
+	for _, field := range filter.IsNull {
+		query += " AND " + nullableFields[field] + " IS NULL"
+	}
+	for _, field := range filter.NotNull {
+		query += " AND " + nullableFields[field] + " IS NOT NULL"
+	}
+
+	return query, args
+}
+
+// nullableFields - поля, для которых поддерживаются фильтры null/notnull.
+// Пустая строка в текстовых полях считается отсутствием значения.
+var nullableFields = map[string]string{
+	"patronymic":  "NULLIF(patronymic, '')",
+	"age":         "age",
+	"gender":      "NULLIF(gender, '')",
+	"nationality": "NULLIF(nationality, '')",
+}
+
+// ValidNullField сообщает, поддерживается ли фильтр null/notnull для поля.
+func ValidNullField(field string) bool {
+	_, ok := nullableFields[field]
+	return ok
+}
+
+func lowerAll(values []string) []string {
+	lower := make([]string, len(values))
+	for i, v := range values {
+		lower[i] = strings.ToLower(v)
+	}
+	return lower
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"junior-test/db"
 	"junior-test/pkg/types"
@@ -21,6 +23,7 @@ func TestMain(t *testing.T) {
 		t.Fatalf("Error initializing test database: %v", err)
 	}
 	defer db.CloseDB()
+	migrateTestDB(t, datebase)
 
 	repo := NewSQLPersonRepository(datebase)
 	person := &types.Person{
@@ -28,11 +31,12 @@ func TestMain(t *testing.T) {
 		Surname: "Samsonov",
 	}
 
-	id, err := repo.CreatePerson(person)
+	id, err := repo.CreatePerson(person, "test")
 
 	if err != nil {
 		t.Fatalf("Expected no error, but got %v", err)
 	}
+	defer cleanupPeople(repo, id)
 
 	fmt.Println("!!!!!!!!!!!!!!!! Test CreatePerson succes !!!!!!!!!!!!!!!! ")
 
@@ -52,7 +56,7 @@ func TestMain(t *testing.T) {
 		Name: "Olegsandr",
 	}
 
-	err = repo.UpdatePerson(id, person3)
+	_, err = repo.UpdatePerson(id, person3, nil, "test")
 
 	if err != nil {
 		t.Fatalf("Error update: %v", err)
@@ -66,7 +70,7 @@ func TestMain(t *testing.T) {
 
 	fmt.Println("!!!!!!!!!!!!!!!!  Test UpdatePerson succes !!!!!!!!!!!!!!!! ")
 
-	err = repo.DeletePerson(id)
+	err = repo.DeletePerson(id, nil, "test")
 	if err != nil {
 		t.Fatalf("Error deleting %v", err)
 	}
@@ -80,6 +84,189 @@ func TestMain(t *testing.T) {
 	fmt.Println("!!!!!!!!!!!!!!!! Test DeletePerson succes !!!!!!!!!!!!!!!!")
 }
 
+// migrateTestDB применяет миграции к тестовой базе до обращения к таблицам:
+// образ Postgres не создает схему при инициализации.
+func migrateTestDB(t *testing.T, database *sql.DB) {
+	t.Helper()
+	if err := db.MigrateUp(database); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+}
+
+// cleanupPeople окончательно удаляет тестовые записи, чтобы они не влияли на следующие запуски.
+func cleanupPeople(repo *SQLPersonRepository, ids ...int) {
+	for _, id := range ids {
+		repo.DeletePerson(id, nil, "test")
+		repo.PurgePerson(id, "test")
+	}
+}
+
+func TestPersonVersion(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	id, err := repo.CreatePerson(&types.Person{Name: "Oleg", Surname: "Samsonov"}, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	person, _ := repo.GetPersonByID(id)
+	created := person.Version
+
+	version, err := repo.UpdatePerson(id, &types.Person{Name: "Olegsandr"}, []int{created}, "test")
+	if err != nil || version != created+1 {
+		t.Fatalf("Expected version %d, got %d, %v", created+1, version, err)
+	}
+
+	// Устаревшая версия не должна позволить изменить или удалить запись
+	if _, err = repo.UpdatePerson(id, &types.Person{Name: "Oleg"}, []int{created}, "test"); err != ErrVersionMismatch {
+		t.Fatalf("Expected version mismatch, got %v", err)
+	}
+	if err = repo.DeletePerson(id, []int{created}, "test"); err != ErrVersionMismatch {
+		t.Fatalf("Expected version mismatch, got %v", err)
+	}
+	if err = repo.DeletePerson(id, []int{version}, "test"); err != nil {
+		t.Fatalf("Error deleting %v", err)
+	}
+	if _, err = repo.UpdatePerson(id, &types.Person{Name: "Oleg"}, nil, "test"); err != sql.ErrNoRows {
+		t.Fatalf("Expected deleted person not to be found, got %v", err)
+	}
+}
+
+func TestRestorePerson(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	id, err := repo.CreatePerson(&types.Person{Name: "Oleg", Surname: "Samsonov"}, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	if _, err = repo.RestorePerson(id, nil, "test"); err != sql.ErrNoRows {
+		t.Fatalf("Expected only deleted person to be restored, got %v", err)
+	}
+
+	if err = repo.DeletePerson(id, nil, "test"); err != nil {
+		t.Fatalf("Error deleting %v", err)
+	}
+	if _, err = repo.RestorePerson(id, nil, "test"); err != nil {
+		t.Fatalf("Error restoring %v", err)
+	}
+
+	if person, err := repo.GetPersonByID(id); person == nil {
+		t.Fatalf("Error restoring %v", err)
+	}
+}
+
+func TestPersonHistory(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	id, err := repo.CreatePerson(&types.Person{Name: "Oleg", Surname: "Samsonov"}, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	if _, err = repo.UpdatePerson(id, &types.Person{Name: "Olegsandr"}, nil, "test"); err != nil {
+		t.Fatalf("Error update: %v", err)
+	}
+	if err = repo.DeletePerson(id, nil, "test"); err != nil {
+		t.Fatalf("Error deleting %v", err)
+	}
+	if _, err = repo.RestorePerson(id, nil, "test"); err != nil {
+		t.Fatalf("Error restoring %v", err)
+	}
+
+	// История: создание, изменение, удаление и восстановление, сначала новые
+	history, total, err := repo.GetPersonHistory(id, 1, 10)
+	if err != nil || total != 4 {
+		t.Fatalf("Unexpected history: %d records, %v", total, err)
+	}
+	for i, action := range []string{types.HistoryRestore, types.HistoryDelete, types.HistoryUpdate, types.HistoryCreate} {
+		if history[i].Action != action || history[i].Actor != "test" {
+			t.Fatalf("History record %d: %s by %s, expected %s", i, history[i].Action, history[i].Actor, action)
+		}
+	}
+	if history[3].Before != nil || history[2].Before == nil || history[2].After == nil {
+		t.Fatalf("Unexpected history snapshots: %+v", history)
+	}
+}
+
+func TestPurgePerson(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	id, err := repo.CreatePerson(&types.Person{Name: "Oleg", Surname: "Samsonov"}, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	if err = repo.PurgePerson(id, "test"); err != sql.ErrNoRows {
+		t.Fatalf("Expected only deleted person to be purged, got %v", err)
+	}
+
+	if err = repo.DeletePerson(id, nil, "test"); err != nil {
+		t.Fatalf("Error deleting %v", err)
+	}
+	if err = repo.PurgePerson(id, "test"); err != nil {
+		t.Fatalf("Error purging %v", err)
+	}
+
+	if person, _ := repo.GetPersonIncludingDeleted(id); person != nil {
+		t.Fatalf("Purged person must not exist")
+	}
+
+	// История переживает окончательное удаление и завершается действием purge
+	history, total, err := repo.GetPersonHistory(id, 1, 10)
+	if err != nil || total != 3 || history[0].Action != types.HistoryPurge || history[0].Before == nil || history[0].After != nil {
+		t.Fatalf("Unexpected history after purge: %d records, %v", total, err)
+	}
+}
+
 func TestFiltr(t *testing.T) {
 	var persons = []*types.Person{
 		{
@@ -123,13 +310,14 @@ func TestFiltr(t *testing.T) {
 		t.Fatalf("Error initializing test database: %v", err)
 	}
 	defer db.CloseDB()
+	migrateTestDB(t, datebase)
 
 	repo := NewSQLPersonRepository(datebase)
 
 	var ids []int
 
 	for _, pers := range persons {
-		id, err := repo.CreatePerson(pers)
+		id, err := repo.CreatePerson(pers, "test")
 		ids = append(ids, id)
 		if err != nil {
 			t.Fatalf("Expected no error, but got %v", err)
@@ -138,15 +326,16 @@ func TestFiltr(t *testing.T) {
 
 	defer func() {
 		for _, id := range ids {
-			err := repo.DeletePerson(id)
+			err := repo.DeletePerson(id, nil, "test")
 			if err != nil {
 				t.Fatalf("Error %v", err)
 			}
 		}
+		cleanupPeople(repo, ids...)
 	}()
 
 	var personFilter1 = types.PersonFilter{
-		Gender: "Female",
+		Gender: types.StringList{"Female"},
 	}
 
 	filter1, _ := repo.FilterListPeople(personFilter1)
@@ -155,10 +344,11 @@ func TestFiltr(t *testing.T) {
 		t.Fatalf("The answer does not satisfy the request: %d != 1", len(filter1))
 	}
 
+	minAge, maxAge := 20, 29
 	var personFilter2 = types.PersonFilter{
-		Gender: "Male",
-		MinAge: 20,
-		MaxAge: 29,
+		Gender: types.StringList{"Male"},
+		MinAge: &minAge,
+		MaxAge: &maxAge,
 	}
 
 	filter2, _ := repo.FilterListPeople(personFilter2)
@@ -179,3 +369,188 @@ func TestFiltr(t *testing.T) {
 	}
 
 }
+
+func TestCreatePeople(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	people := []*types.Person{
+		{Name: "Oleg", Surname: "Samsonov", Age: 29, Nationalities: []types.Nationality{{CountryID: "RU", Probability: 0.6}, {CountryID: "UA", Probability: 0.3}}},
+		{Name: "Katerina", Surname: "Samsonova", Age: 17},
+	}
+	if err := repo.CreatePeople(people, "test"); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	for _, person := range people {
+		defer cleanupPeople(repo, person.ID)
+
+		created, err := repo.GetPersonByID(person.ID)
+		if err != nil || created == nil || created.Name != person.Name || created.Age != person.Age {
+			t.Fatalf("Person %d was not created correctly: %+v, %v", person.ID, created, err)
+		}
+		if len(created.Nationalities) != len(person.Nationalities) {
+			t.Fatalf("Nationalities of person %d were not created: %+v", person.ID, created.Nationalities)
+		}
+	}
+}
+
+func TestExportPeople(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	// Больше одной пачки FETCH, чтобы проверить чтение курсора до конца
+	people := make([]*types.Person, exportFetchSize+1)
+	for i := range people {
+		people[i] = &types.Person{Name: "Export", Surname: "Samsonov", Age: i}
+	}
+	if err := repo.CreatePeople(people, "test"); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for _, person := range people {
+		defer cleanupPeople(repo, person.ID)
+	}
+
+	var exported []*types.Person
+	filter := types.PersonFilter{Name: "Export", Sort: "-age", Page: 1, PageSize: 10}
+	err = repo.ExportPeople(context.Background(), filter, func(p *types.Person) error {
+		exported = append(exported, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(exported) != len(people) {
+		t.Fatalf("Expected %d exported people, got %d", len(people), len(exported))
+	}
+	if exported[0].Age != len(people)-1 || exported[len(exported)-1].Age != 0 {
+		t.Fatalf("Expected people sorted by age descending, got %d..%d", exported[0].Age, exported[len(exported)-1].Age)
+	}
+}
+
+func TestSaveEnrichment(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	// Genderize и nationalize были недоступны: сохранен только возраст
+	person := &types.Person{Name: "Oleg", Surname: "Samsonov", Age: 29, Gender: "male", EnrichmentFields: map[string]string{
+		types.FieldAge: types.EnrichmentComplete, types.FieldGender: types.EnrichmentFailed, types.FieldNationality: types.EnrichmentFailed,
+	}}
+	id, err := repo.CreatePerson(person, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	created, err := repo.GetPersonByID(id)
+	if err != nil || created == nil || created.EnrichmentStatus != types.EnrichmentPartial || created.Age != 29 || created.Gender != "" {
+		t.Fatalf("Partially enriched person was not created correctly: %+v, %v", created, err)
+	}
+
+	// Возраст уже обогащен и не перезаписывается, сохраняется только пол
+	created.Age = 99
+	created.Gender, created.GenderProbability = "male", 0.9
+	created.SetFieldStatus(types.FieldGender, types.EnrichmentComplete)
+	stale := *created
+	stale.Version++
+	if _, err := repo.SaveEnrichment(id, &stale, "test"); err != ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch, got %v", err)
+	}
+	version, err := repo.SaveEnrichment(id, created, "test")
+	if err != nil || version != created.Version+1 {
+		t.Fatalf("Expected version %d, got %d, %v", created.Version+1, version, err)
+	}
+
+	updated, err := repo.GetPersonByID(id)
+	if err != nil || updated == nil || updated.Gender != "male" || updated.Age != 29 {
+		t.Fatalf("Enrichment was not saved: %+v, %v", updated, err)
+	}
+	if updated.EnrichmentStatus != types.EnrichmentPartial || updated.EnrichmentFields[types.FieldNationality] != types.EnrichmentFailed {
+		t.Fatalf("Unexpected enrichment status: %s, %v", updated.EnrichmentStatus, updated.EnrichmentFields)
+	}
+}
+
+func TestPatchEnrichedFields(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	datebase, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("Error initializing test database: %v", err)
+	}
+	defer db.CloseDB()
+	migrateTestDB(t, datebase)
+
+	repo := NewSQLPersonRepository(datebase)
+
+	// Ни одно поле не удалось обогатить
+	person := &types.Person{Name: "Oleg", Surname: "Samsonov", EnrichmentFields: map[string]string{
+		types.FieldAge: types.EnrichmentFailed, types.FieldGender: types.EnrichmentFailed, types.FieldNationality: types.EnrichmentFailed,
+	}}
+	id, err := repo.CreatePerson(person, "test")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer cleanupPeople(repo, id)
+
+	if _, err := repo.PatchPerson(id, map[string]interface{}{"age": 55}, nil, "test"); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	edited, err := repo.GetPersonByID(id)
+	if err != nil || edited == nil || !edited.FieldEnriched(types.FieldAge) || edited.EnrichmentStatus != types.EnrichmentPartial {
+		t.Fatalf("Manually set age must be marked enriched: %+v, %v", edited, err)
+	}
+
+	// Обогащение не перезаписывает возраст, заданный вручную
+	enriched := *edited
+	enriched.Age = 29
+	enriched.Gender, enriched.GenderProbability = "male", 0.9
+	enriched.SetFieldStatus(types.FieldGender, types.EnrichmentComplete)
+	if _, err := repo.SaveEnrichment(id, &enriched, "test"); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	updated, err := repo.GetPersonByID(id)
+	if err != nil || updated == nil || updated.Age != 55 || updated.Gender != "male" {
+		t.Fatalf("Manual age was lost: %+v, %v", updated, err)
+	}
+
+	// Очищенное поле снова ожидает обогащения
+	if _, err := repo.PatchPerson(id, map[string]interface{}{"age": nil}, nil, "test"); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	cleared, err := repo.GetPersonByID(id)
+	if err != nil || cleared == nil || cleared.FieldEnriched(types.FieldAge) || cleared.Age != 0 {
+		t.Fatalf("Cleared age must not be marked enriched: %+v, %v", cleared, err)
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"junior-test/pkg/translit"
+	"junior-test/pkg/types"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// Режимы сравнения полей ФИО в фильтре.
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+)
+
+// Режимы поиска по параметру q.
+const (
+	SearchFullText = "fulltext"
+	SearchFuzzy    = "fuzzy"
+)
+
+// ValidSearch сообщает, поддерживается ли режим поиска (пустой - fulltext).
+func ValidSearch(search string) bool {
+	return search == "" || search == SearchFullText || search == SearchFuzzy
+}
+
+// ErrCursorRelevance возвращается при keyset-пагинации списка, упорядоченного по релевантности.
+var ErrCursorRelevance = errors.New("cursor pagination is not supported for relevance order, specify sort")
+
+// RelevanceOrder сообщает, что записи упорядочиваются по релевантности поиска по Q
+// (поиск без явной сортировки). Такой порядок не поддерживает keyset-пагинацию:
+// релевантность не входит в курсор.
+func RelevanceOrder(filter types.PersonFilter) bool {
+	return filter.Sort == "" && (filter.Search == SearchFuzzy || SearchQuery(filter.Q) != "")
+}
+
+// ValidMatch сообщает, поддерживается ли режим сравнения (пустой - exact).
+func ValidMatch(match string) bool {
+	switch match {
+	case "", MatchExact, MatchPrefix, MatchContains:
+		return true
+	}
+	return false
+}
+
+// nameCondition возвращает условие сравнения поля ФИО без учета регистра
+// и значение параметра для него. Для prefix/contains используется LIKE,
+// который обслуживается trigram-индексами.
+func nameCondition(column, value, match string, param int) (string, interface{}) {
+	switch match {
+	case MatchPrefix:
+		return fmt.Sprintf(" AND LOWER(%s) LIKE LOWER($%d)", column, param), escapeLike(value) + "%"
+	case MatchContains:
+		return fmt.Sprintf(" AND LOWER(%s) LIKE LOWER($%d)", column, param), "%" + escapeLike(value) + "%"
+	default:
+		return fmt.Sprintf(" AND LOWER(%s) = LOWER($%d)", column, param), value
+	}
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// SearchQuery преобразует строку поиска в запрос to_tsquery: каждое слово
+// ищется по префиксу, все слова должны присутствовать ("dmit ush" -> "dmit:* & ush:*").
+// Возвращает пустую строку, если в q нет ни одного слова.
+func SearchQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// searchKey возвращает нормализованное ФИО для нечеткого поиска.
+func searchKey(person *types.Person) string {
+	return translit.Normalize(person.Name + " " + person.Surname + " " + person.Patronymic)
+}
+
+// backfillBatchSize - количество записей, ключи которых заполняются одним запросом.
+const backfillBatchSize = 1000
+
+// BackfillSearchKeys заполняет ключ нечеткого поиска для записей, созданных
+// до его появления. Вызывается при запуске после применения миграций.
+// Записи обрабатываются пачками по возрастанию ID: одно чтение и одно обновление на пачку.
+func (r *SQLPersonRepository) BackfillSearchKeys() error {
+	total, lastID := 0, 0
+	for {
+		rows, err := r.DB.Query("SELECT id, name, surname, COALESCE(patronymic, '') FROM people WHERE search_key IS NULL AND id > $1 ORDER BY id LIMIT $2",
+			lastID, backfillBatchSize)
+		if err != nil {
+			log.Printf("Error selecting people without search key: %v", err)
+			return err
+		}
+
+		var ids []int64
+		var keys []string
+		for rows.Next() {
+			var id int64
+			person := &types.Person{}
+			if err := rows.Scan(&id, &person.Name, &person.Surname, &person.Patronymic); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+			keys = append(keys, searchKey(person))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		_, err = r.DB.Exec("UPDATE people SET search_key = t.key FROM unnest($1::int[], $2::text[]) AS t(id, key) WHERE people.id = t.id",
+			pq.Array(ids), pq.Array(keys))
+		if err != nil {
+			log.Printf("Error updating search keys of %d people: %v", len(ids), err)
+			return err
+		}
+		total += len(ids)
+		lastID = int(ids[len(ids)-1])
+	}
+
+	if total > 0 {
+		log.Printf("Filled search keys for %d people", total)
+	}
+	return nil
+}
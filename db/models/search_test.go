@@ -0,0 +1,35 @@
+package db
+
+import "testing"
+
+func TestSearchQuery(t *testing.T) {
+	cases := map[string]string{
+		"Dmit Ush":        "dmit:* & ush:*",
+		"  o'brien & | !": "o:* & brien:*",
+		"Дмитрий":         "дмитрий:*",
+		"&&&":             "",
+	}
+
+	for q, expected := range cases {
+		if got := SearchQuery(q); got != expected {
+			t.Fatalf("SearchQuery(%q) = %q, expected %q", q, got, expected)
+		}
+	}
+}
+
+func TestNameCondition(t *testing.T) {
+	condition, arg := nameCondition("surname", "Sam_s%", MatchContains, 2)
+	if condition != " AND LOWER(surname) LIKE LOWER($2)" || arg != `%Sam\_s\%%` {
+		t.Fatalf("Unexpected contains condition: %s %v", condition, arg)
+	}
+
+	_, arg = nameCondition("surname", "Sam", MatchPrefix, 1)
+	if arg != "Sam%" {
+		t.Fatalf("Unexpected prefix pattern: %v", arg)
+	}
+
+	condition, arg = nameCondition("name", "Oleg", "", 1)
+	if condition != " AND LOWER(name) = LOWER($1)" || arg != "Oleg" {
+		t.Fatalf("Unexpected exact condition: %s %v", condition, arg)
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"fmt"
+	"junior-test/pkg/types"
+	"strings"
+)
+
+// SortField - поле сортировки списка людей.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// sortColumn описывает поле, по которому разрешена сортировка.
+// Для столбцов, допускающих NULL, выражение заменяет NULL значением,
+// чтобы порядок был полным и keyset-пагинация не пропускала записи.
+type sortColumn struct {
+	expr  string
+	value func(p *types.Person) interface{}
+}
+
+// sortColumns - белый список полей сортировки.
+var sortColumns = map[string]sortColumn{
+	"id":          {"id", func(p *types.Person) interface{} { return p.ID }},
+	"name":        {"name", func(p *types.Person) interface{} { return p.Name }},
+	"surname":     {"surname", func(p *types.Person) interface{} { return p.Surname }},
+	"patronymic":  {"COALESCE(patronymic, '')", func(p *types.Person) interface{} { return p.Patronymic }},
+	"age":         {"COALESCE(age, 0)", func(p *types.Person) interface{} { return p.Age }},
+	"gender":      {"COALESCE(gender, '')", func(p *types.Person) interface{} { return p.Gender }},
+	"nationality": {"COALESCE(nationality, '')", func(p *types.Person) interface{} { return p.Nationality }},
+	"created_at":  {"created_at", func(p *types.Person) interface{} { return p.CreatedAt }},
+	"updated_at":  {"updated_at", func(p *types.Person) interface{} { return p.UpdatedAt }},
+}
+
+// ParseSort разбирает строку вида "-age,surname": поля через запятую,
+// минус перед полем означает сортировку по убыванию.
+// Если id не указан, он добавляется последним для однозначного порядка.
+func ParseSort(sort string) ([]SortField, error) {
+	var fields []SortField
+	seen := map[string]bool{}
+	hasID := false
+
+	if strings.TrimSpace(sort) != "" {
+		for _, part := range strings.Split(sort, ",") {
+			part = strings.TrimSpace(part)
+			field := SortField{Name: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+
+			if _, ok := sortColumns[field.Name]; !ok {
+				return nil, fmt.Errorf("unknown sort field %q", field.Name)
+			}
+			if seen[field.Name] {
+				return nil, fmt.Errorf("sort field %q is specified more than once", field.Name)
+			}
+			seen[field.Name] = true
+			hasID = hasID || field.Name == "id"
+
+			fields = append(fields, field)
+		}
+	}
+
+	if !hasID {
+		fields = append(fields, SortField{Name: "id"})
+	}
+
+	return fields, nil
+}
+
+// SortKeys возвращает значения полей сортировки (кроме id) для записи person.
+// Используется для формирования курсора keyset-пагинации.
+func SortKeys(fields []SortField, person *types.Person) []interface{} {
+	var keys []interface{}
+	for _, field := range fields {
+		if field.Name != "id" {
+			keys = append(keys, sortColumns[field.Name].value(person))
+		}
+	}
+	return keys
+}
+
+// orderByClause строит ORDER BY для полей сортировки.
+func orderByClause(fields []SortField) string {
+	var parts []string
+	for _, field := range fields {
+		part := sortColumns[field.Name].expr
+		if field.Desc {
+			part += " DESC"
+		}
+		parts = append(parts, part)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keysetCondition строит условие "строго после позиции курсора" для
+// произвольного набора полей и направлений сортировки:
+// (f1 > v1) OR (f1 = v1 AND f2 > v2) OR ...
+func keysetCondition(fields []SortField, after *types.CursorPosition, paramCount int) (string, []interface{}, error) {
+	var values []interface{}
+	keys := after.Keys
+	for _, field := range fields {
+		if field.Name == "id" {
+			values = append(values, after.ID)
+			continue
+		}
+		if len(keys) == 0 {
+			return "", nil, fmt.Errorf("cursor does not match sort fields")
+		}
+		values = append(values, keys[0])
+		keys = keys[1:]
+	}
+	if len(keys) != 0 {
+		return "", nil, fmt.Errorf("cursor does not match sort fields")
+	}
+
+	var disjuncts []string
+	for i, field := range fields {
+		var conjuncts []string
+		for j := 0; j < i; j++ {
+			conjuncts = append(conjuncts, fmt.Sprintf("%s = $%d", sortColumns[fields[j].Name].expr, paramCount+j))
+		}
+
+		op := ">"
+		if field.Desc {
+			op = "<"
+		}
+		conjuncts = append(conjuncts, fmt.Sprintf("%s %s $%d", sortColumns[field.Name].expr, op, paramCount+i))
+
+		disjuncts = append(disjuncts, "("+strings.Join(conjuncts, " AND ")+")")
+	}
+
+	return " AND (" + strings.Join(disjuncts, " OR ") + ")", values, nil
+}
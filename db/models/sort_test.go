@@ -0,0 +1,60 @@
+package db
+
+import (
+	"junior-test/pkg/types"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	fields, err := ParseSort("-age, surname")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	expected := []SortField{{Name: "age", Desc: true}, {Name: "surname"}, {Name: "id"}}
+	if len(fields) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, fields)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, fields)
+		}
+	}
+
+	if fields, _ = ParseSort(""); len(fields) != 1 || fields[0].Name != "id" {
+		t.Fatalf("Default sort must be by id, got %v", fields)
+	}
+
+	if fields, _ = ParseSort("-id"); len(fields) != 1 || !fields[0].Desc {
+		t.Fatalf("Explicit id must not be duplicated, got %v", fields)
+	}
+
+	for _, bad := range []string{"password", "age,age", "name,"} {
+		if _, err := ParseSort(bad); err == nil {
+			t.Fatalf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	fields, _ := ParseSort("-age,surname")
+	after := &types.CursorPosition{Keys: []interface{}{30.0, "Pashkovskiy"}, ID: 7}
+
+	condition, values, err := keysetCondition(fields, after, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	expected := " AND ((COALESCE(age, 0) < $3) OR (COALESCE(age, 0) = $3 AND surname > $4) OR (COALESCE(age, 0) = $3 AND surname = $4 AND id > $5))"
+	if condition != expected {
+		t.Fatalf("Unexpected condition:\n%s\nexpected:\n%s", condition, expected)
+	}
+
+	if len(values) != 3 || values[2] != 7 {
+		t.Fatalf("Unexpected values: %v", values)
+	}
+
+	if _, _, err := keysetCondition(fields, &types.CursorPosition{ID: 7}, 1); err == nil {
+		t.Fatal("Expected error for cursor without sort keys")
+	}
+}
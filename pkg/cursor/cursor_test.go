@@ -0,0 +1,38 @@
+package cursor
+
+import "testing"
+
+type position struct {
+	Keys []interface{} `json:"k"`
+	ID   int           `json:"id"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+
+	token, err := Encode(secret, position{Keys: []interface{}{"Samsonov"}, ID: 42})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	var decoded position
+	if err := Decode(secret, token, &decoded); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if decoded.ID != 42 || len(decoded.Keys) != 1 || decoded.Keys[0] != "Samsonov" {
+		t.Fatalf("Unexpected payload: %+v", decoded)
+	}
+}
+
+func TestTampered(t *testing.T) {
+	token, _ := Encode([]byte("secret"), position{ID: 42})
+	forged, _ := Encode([]byte("other"), position{ID: 1})
+
+	var decoded position
+	for _, bad := range []string{"", "garbage", token + "x", forged} {
+		if err := Decode([]byte("secret"), bad, &decoded); err != ErrInvalid {
+			t.Fatalf("Expected ErrInvalid for %q, got %v", bad, err)
+		}
+	}
+}
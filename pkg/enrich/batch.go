@@ -0,0 +1,155 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// MaxBatchNames - максимальное количество имен в одном запросе к API
+// (ограничение agify, genderize и nationalize).
+const MaxBatchNames = 10
+
+// BatchEnricher - источник, который умеет обогащать несколько человек
+// одним запросом к API (?name[]=a&name[]=b).
+type BatchEnricher interface {
+	Enricher
+	// EnrichBatch заполняет поля каждого из people и возвращает ошибки по их индексам.
+	EnrichBatch(ctx context.Context, people []*types.Person) []error
+}
+
+// getBatch запрашивает API сразу для нескольких имен и возвращает ответы
+// в порядке имен в запросе.
+func (c apiClient) getBatch(ctx context.Context, names []string) ([]json.RawMessage, error) {
+	var responses []json.RawMessage
+	if err := c.do(ctx, url.Values{"name[]": names}, &responses); err != nil {
+		return nil, err
+	}
+	if len(responses) != len(names) {
+		return nil, fmt.Errorf("API returned %d results for %d names", len(responses), len(names))
+	}
+	return responses, nil
+}
+
+// enrichBatch обогащает people частями по MaxBatchNames имен; apply разбирает
+// ответ API для одного человека. Имена со свежим ответом в кэше не запрашиваются.
+// Ошибка запроса относится ко всем людям части.
+func (c apiClient) enrichBatch(ctx context.Context, people []*types.Person, apply func(person *types.Person, data json.RawMessage) error) []error {
+	errs := make([]error, len(people))
+	responses := make([]json.RawMessage, len(people))
+
+	// Индексы людей, ответов для которых нет в кэше
+	var missing []int
+	for i, person := range people {
+		if response, ok := c.cache.get(ctx, c.cacheKey(person.Name)); ok {
+			responses[i] = response
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	for start := 0; start < len(missing); start += MaxBatchNames {
+		end := start + MaxBatchNames
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		names := make([]string, 0, end-start)
+		for _, i := range missing[start:end] {
+			names = append(names, people[i].Name)
+		}
+
+		batch, err := c.getBatch(ctx, names)
+		for k, i := range missing[start:end] {
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			responses[i] = batch[k]
+			c.cache.set(ctx, c.cacheKey(people[i].Name), batch[k])
+		}
+	}
+
+	for i, person := range people {
+		if errs[i] == nil {
+			errs[i] = apply(person, responses[i])
+		}
+	}
+	return errs
+}
+
+// RunBatch обогащает сразу несколько человек. Каждое имя (без учета регистра)
+// запрашивается один раз, источники BatchEnricher получают имена пачками,
+// остальные - по одному. Как и в Run, поля обогащаются независимо: успешно
+// обогащенные поля получают статус complete, даже если другие источники недоступны.
+// Возвращает ошибку для каждого из people (nil - обогащены все поля).
+func RunBatch(ctx context.Context, people []*types.Person, enrichers []Enricher) []error {
+	// Уникальные имена: owner[i] - индекс имени people[i] в unique
+	var unique []*types.Person
+	owner := make([]int, len(people))
+	seen := map[string]int{}
+	for i, person := range people {
+		key := strings.ToLower(strings.TrimSpace(person.Name))
+		j, ok := seen[key]
+		if !ok {
+			j = len(unique)
+			seen[key] = j
+			unique = append(unique, &types.Person{Name: person.Name})
+		}
+		owner[i] = j
+	}
+
+	// Источники работают параллельно и заполняют только свои поля
+	sourceErrs := make([][]error, len(enrichers))
+	var wg sync.WaitGroup
+	for k, e := range enrichers {
+		wg.Add(1)
+		go func(k int, e Enricher) {
+			defer wg.Done()
+			if batch, ok := e.(BatchEnricher); ok {
+				sourceErrs[k] = batch.EnrichBatch(ctx, unique)
+				return
+			}
+			sourceErrs[k] = make([]error, len(unique))
+			for j, person := range unique {
+				sourceErrs[k][j] = e.Enrich(ctx, person)
+			}
+		}(k, e)
+	}
+	wg.Wait()
+
+	errs := make([]error, len(people))
+	for i, person := range people {
+		j := owner[i]
+		source := unique[j]
+		personErrs := make([]error, len(enrichers))
+		for k, e := range enrichers {
+			if err := sourceErrs[k][j]; err != nil {
+				personErrs[k] = fmt.Errorf("%s: %w", e.Name(), err)
+				continue
+			}
+			copyField(person, source, e.Field())
+			person.SetFieldStatus(e.Field(), types.EnrichmentComplete)
+		}
+		errs[i] = combineErrors(personErrs)
+	}
+
+	return errs
+}
+
+// copyField переносит значения обогащенного поля field из source в person.
+func copyField(person, source *types.Person, field string) {
+	switch field {
+	case types.FieldAge:
+		person.Age, person.AgeCount = source.Age, source.AgeCount
+	case types.FieldGender:
+		person.Gender, person.GenderProbability = source.Gender, source.GenderProbability
+	case types.FieldNationality:
+		person.Nationality = source.Nationality
+		person.Nationalities = append([]types.Nationality(nil), source.Nationalities...)
+	}
+}
@@ -0,0 +1,149 @@
+package enrich
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore - разделяемое хранилище ответов API (таблица enrichment_cache):
+// переживает перезапуск и общее для всех реплик.
+type CacheStore interface {
+	// GetEnrichment возвращает ответ, полученный не раньше since, и время его получения;
+	// nil, если такого ответа нет.
+	GetEnrichment(ctx context.Context, provider, name, country string, since time.Time) (json.RawMessage, time.Time, error)
+	// SaveEnrichment сохраняет ответ, полученный в fetchedAt, заменяя прежний.
+	SaveEnrichment(ctx context.Context, provider, name, country string, response json.RawMessage, fetchedAt time.Time) error
+}
+
+// cacheKey - ключ кэша: источник, нормализованное имя и подсказка страны.
+type cacheKey struct {
+	provider, name, country string
+}
+
+func newCacheKey(provider, name, country string) cacheKey {
+	return cacheKey{
+		provider: provider,
+		name:     strings.ToLower(strings.Join(strings.Fields(name), " ")),
+		country:  strings.ToUpper(strings.TrimSpace(country)),
+	}
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	response  json.RawMessage
+	fetchedAt time.Time
+}
+
+// Cache хранит ответы API обогащения в течение ttl: последние size ответов -
+// в памяти процесса (LRU), все - в store, если оно задано.
+// Ответы "имя не найдено" кэшируются так же, как и остальные.
+type Cache struct {
+	ttl   time.Duration
+	size  int
+	store CacheStore
+
+	mu    sync.Mutex
+	items map[cacheKey]*list.Element
+	order *list.List // от недавно использованных к давно использованным
+
+	now func() time.Time
+}
+
+// NewCache создает кэш со сроком жизни ответов ttl и не более size ответами в памяти.
+// store может быть nil - тогда ответы хранятся только в памяти.
+func NewCache(ttl time.Duration, size int, store CacheStore) *Cache {
+	return &Cache{
+		ttl:   ttl,
+		size:  size,
+		store: store,
+		items: map[cacheKey]*list.Element{},
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+// get возвращает свежий ответ из памяти или из хранилища.
+// Ошибки хранилища не прерывают обогащение: ответ запрашивается у API заново.
+// nil-кэш всегда пуст.
+func (c *Cache) get(ctx context.Context, key cacheKey) (json.RawMessage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	since := c.now().Add(-c.ttl)
+
+	c.mu.Lock()
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if entry.fetchedAt.After(since) {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			return entry.response, true
+		}
+		c.remove(element)
+	}
+	c.mu.Unlock()
+
+	if c.store == nil {
+		return nil, false
+	}
+
+	response, fetchedAt, err := c.store.GetEnrichment(ctx, key.provider, key.name, key.country, since)
+	if err != nil {
+		log.Printf("Error reading enrichment cache for %s %q: %v", key.provider, key.name, err)
+		return nil, false
+	}
+	if response == nil {
+		return nil, false
+	}
+
+	c.add(cacheEntry{key: key, response: response, fetchedAt: fetchedAt})
+	return response, true
+}
+
+// set сохраняет ответ, только что полученный от API.
+func (c *Cache) set(ctx context.Context, key cacheKey, response json.RawMessage) {
+	if c == nil {
+		return
+	}
+	entry := cacheEntry{key: key, response: response, fetchedAt: c.now()}
+	c.add(entry)
+
+	if c.store == nil {
+		return
+	}
+	if err := c.store.SaveEnrichment(ctx, key.provider, key.name, key.country, response, entry.fetchedAt); err != nil {
+		log.Printf("Error saving enrichment cache for %s %q: %v", key.provider, key.name, err)
+	}
+}
+
+// add помещает ответ в память, вытесняя давно использованные при превышении size.
+func (c *Cache) add(entry cacheEntry) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[entry.key]; ok {
+		element.Value = &entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[entry.key] = c.order.PushFront(&entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove удаляет элемент из памяти; вызывается под c.mu.
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,153 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryStore - хранилище кэша в памяти вместо таблицы enrichment_cache.
+type memoryStore map[cacheKey]cacheEntry
+
+func (s memoryStore) GetEnrichment(ctx context.Context, provider, name, country string, since time.Time) (json.RawMessage, time.Time, error) {
+	entry, ok := s[cacheKey{provider, name, country}]
+	if !ok || !entry.fetchedAt.After(since) {
+		return nil, time.Time{}, nil
+	}
+	return entry.response, entry.fetchedAt, nil
+}
+
+func (s memoryStore) SaveEnrichment(ctx context.Context, provider, name, country string, response json.RawMessage, fetchedAt time.Time) error {
+	key := cacheKey{provider, name, country}
+	s[key] = cacheEntry{key: key, response: response, fetchedAt: fetchedAt}
+	return nil
+}
+
+func TestCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache(time.Hour, 2, nil)
+
+	cache.set(ctx, newCacheKey("agify", "Oleg", ""), json.RawMessage(`1`))
+	cache.set(ctx, newCacheKey("agify", "Dmitriy", ""), json.RawMessage(`2`))
+
+	// Имя нормализуется: регистр и лишние пробелы не важны
+	if response, ok := cache.get(ctx, newCacheKey("agify", "  OLEG ", "")); !ok || string(response) != "1" {
+		t.Fatalf("Expected cached response for Oleg, got %s, %v", response, ok)
+	}
+
+	// Dmitriy использовался давнее всех и вытесняется
+	cache.set(ctx, newCacheKey("agify", "Katerina", ""), json.RawMessage(`3`))
+	if _, ok := cache.get(ctx, newCacheKey("agify", "Dmitriy", "")); ok {
+		t.Fatal("Expected Dmitriy to be evicted")
+	}
+	if _, ok := cache.get(ctx, newCacheKey("genderize", "Oleg", "")); ok {
+		t.Fatal("Expected responses of different providers to be cached separately")
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := memoryStore{}
+	cache := NewCache(time.Hour, 10, store)
+	cache.now = func() time.Time { return now }
+
+	key := newCacheKey("agify", "Oleg", "")
+	cache.set(ctx, key, json.RawMessage(`1`))
+
+	// Новый процесс с пустой памятью получает ответ из хранилища
+	restarted := NewCache(time.Hour, 10, store)
+	restarted.now = cache.now
+	if response, ok := restarted.get(ctx, key); !ok || string(response) != "1" {
+		t.Fatalf("Expected response from store, got %s, %v", response, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := cache.get(ctx, key); ok {
+		t.Fatal("Expected expired response to be ignored")
+	}
+	if _, ok := restarted.get(ctx, key); ok {
+		t.Fatal("Expected expired response in store to be ignored")
+	}
+}
+
+func TestEnrichCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var results []string
+		for _, name := range r.URL.Query()["name[]"] {
+			results = append(results, fmt.Sprintf(`{"count": 10, "name": %q, "age": %d}`, name, len(name)))
+		}
+		if name := r.URL.Query().Get("name"); name != "" {
+			fmt.Fprintf(w, `{"count": 10, "name": %q, "age": %d}`, name, len(name))
+			return
+		}
+		fmt.Fprint(w, "["+strings.Join(results, ",")+"]")
+	}))
+	defer server.Close()
+
+	agify := NewAgify(server.URL, server.Client(), WithCache(NewCache(time.Hour, 10, nil)))
+
+	person := &types.Person{Name: "Dmitriy"}
+	for i := 0; i < 2; i++ {
+		if err := agify.Enrich(context.Background(), person); err != nil || person.Age != 7 {
+			t.Fatalf("Unexpected enrichment result: %+v, %v", person, err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+
+	// В пакетный запрос попадают только имена, которых нет в кэше
+	people := []*types.Person{{Name: "dmitriy"}, {Name: "Oleg"}}
+	errs := agify.EnrichBatch(context.Background(), people)
+	if errs[0] != nil || errs[1] != nil || people[0].Age != 7 || people[1].Age != 4 {
+		t.Fatalf("Unexpected batch result: %v, %+v, %+v", errs, people[0], people[1])
+	}
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+	if err := agify.Enrich(context.Background(), &types.Person{Name: "Oleg"}); err != nil || requests != 2 {
+		t.Fatalf("Expected Oleg to be cached by batch request, got %d requests, %v", requests, err)
+	}
+}
+
+func TestCacheCountry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		age := 30
+		if r.URL.Query().Get("country_id") == "RU" {
+			age = 40
+		}
+		fmt.Fprintf(w, `{"count": 10, "name": "Dmitriy", "age": %d}`, age)
+	}))
+	defer server.Close()
+
+	// Источники с разными подсказками страны используют общий кэш, но не ответы друг друга
+	cache := NewCache(time.Hour, 10, nil)
+	local := NewAgify(server.URL, server.Client(), WithCache(cache), WithCountry(" ru"))
+	global := NewAgify(server.URL, server.Client(), WithCache(cache))
+
+	for i := 0; i < 2; i++ {
+		person := &types.Person{Name: "Dmitriy"}
+		if err := local.Enrich(context.Background(), person); err != nil || person.Age != 40 {
+			t.Fatalf("Expected age for RU, got %+v, %v", person, err)
+		}
+		person = &types.Person{Name: "Dmitriy"}
+		if err := global.Enrich(context.Background(), person); err != nil || person.Age != 30 {
+			t.Fatalf("Expected age without country hint, got %+v, %v", person, err)
+		}
+	}
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+}
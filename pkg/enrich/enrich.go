@@ -0,0 +1,447 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Адреса публичных API, используемые по умолчанию.
+const (
+	DefaultAgifyURL       = "https://api.agify.io/"
+	DefaultGenderizeURL   = "https://api.genderize.io/"
+	DefaultNationalizeURL = "https://api.nationalize.io/"
+)
+
+// Enricher дополняет данные о человеке сведениями из внешнего источника.
+type Enricher interface {
+	// Name возвращает имя источника (используется в логах и ошибках).
+	Name() string
+	// Field возвращает обогащаемое поле записи (types.FieldAge и т.д.).
+	Field() string
+	// Enrich заполняет поля person, за которые отвечает источник.
+	Enrich(ctx context.Context, person *types.Person) error
+}
+
+// apiClient содержит общую логику обращения к API вида "?name=..." и встраивается в каждый источник.
+type apiClient struct {
+	provider string // имя источника, входит в ключ кэша
+	baseURL  string
+	apiKey   string // ключ платного тарифа, передается параметром apikey
+	country  string // подсказка страны (ISO 3166-1 alpha-2), передается параметром country_id
+	client   *http.Client
+	cache    *Cache // nil - без кэша
+	limiter  *limiter
+	policy   Policy
+	breaker  *breaker
+}
+
+// Option настраивает источник обогащения при создании.
+type Option func(c *apiClient)
+
+// WithCache включает кэширование ответов API в cache.
+func WithCache(cache *Cache) Option {
+	return func(c *apiClient) { c.cache = cache }
+}
+
+// WithAPIKey задает ключ API платного тарифа (пустой - бесплатный тариф).
+func WithAPIKey(key string) Option {
+	return func(c *apiClient) { c.apiKey = key }
+}
+
+// WithCountry задает подсказку страны: API учитывает ее при определении возраста
+// и пола (пустая - без подсказки). Ответы для разных стран кэшируются отдельно.
+func WithCountry(country string) Option {
+	return func(c *apiClient) { c.country = strings.ToUpper(strings.TrimSpace(country)) }
+}
+
+// WithPolicy задает таймаут, повторы и автоматический выключатель запросов к API.
+func WithPolicy(policy Policy) Option {
+	return func(c *apiClient) { c.setPolicy(policy) }
+}
+
+func newAPIClient(provider, baseURL string, client *http.Client, opts []Option) apiClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := apiClient{provider: provider, baseURL: baseURL, client: client, limiter: newLimiter(provider)}
+	c.setPolicy(DefaultPolicy)
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c *apiClient) Name() string { return c.provider }
+
+func (c *apiClient) Quota() types.ProviderQuota { return c.limiter.status() }
+
+// setPolicy задает настройки надежности; состояние выключателя сбрасывается.
+func (c *apiClient) setPolicy(policy Policy) {
+	c.policy = policy
+	c.breaker = newBreaker(policy.BreakerThreshold, policy.BreakerCooldown)
+}
+
+// cacheKey возвращает ключ кэша для ответа API по имени и подсказке страны.
+func (c apiClient) cacheKey(name string) cacheKey {
+	return newCacheKey(c.provider, name, c.country)
+}
+
+// get выполняет запрос к API для указанного имени и декодирует JSON-ответ в out.
+// Если в кэше есть свежий ответ для этого имени, API не запрашивается.
+func (c apiClient) get(ctx context.Context, name string, out interface{}) error {
+	key := c.cacheKey(name)
+	response, ok := c.cache.get(ctx, key)
+	if !ok {
+		if err := c.do(ctx, url.Values{"name": {name}}, &response); err != nil {
+			return err
+		}
+		c.cache.set(ctx, key, response)
+	}
+	return json.Unmarshal(response, out)
+}
+
+// do выполняет GET-запрос к API с параметрами params и декодирует JSON-ответ в out.
+// После сетевых ошибок и ответов 5xx запрос повторяется согласно policy; каждая
+// попытка расходует квоту, а выключатель учитывает вызов целиком - как одну неудачу
+// после исчерпания повторов. Если квота источника исчерпана, запрос не отправляется
+// и возвращается *QuotaError, если источник недоступен - ErrCircuitOpen.
+func (c apiClient) do(ctx context.Context, params url.Values, out interface{}) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if c.apiKey != "" {
+		query.Set("apikey", c.apiKey)
+	}
+	if c.country != "" {
+		query.Set("country_id", c.country)
+	}
+	u.RawQuery = query.Encode()
+
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+
+	var temporary bool
+	for retry := 1; ; retry++ {
+		// Каждое имя в запросе расходует единицу квоты, в том числе при повторе
+		if err = c.limiter.reserve(len(params["name"]) + len(params["name[]"])); err != nil {
+			break
+		}
+		temporary, err = c.attempt(ctx, u.String(), out)
+		if err == nil || !temporary || retry > c.policy.MaxRetries || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.policy.backoff(retry)):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Неудача - только если последняя попытка завершилась временной ошибкой
+	// не по вине вызывающего; успешный ответ источника записывает attempt
+	if temporary && ctx.Err() == nil {
+		c.breaker.record(false)
+	} else {
+		c.breaker.cancel()
+	}
+	return err
+}
+
+// attempt выполняет одну попытку запроса. temporary означает, что ошибка
+// временная (сетевая ошибка, таймаут попытки или ответ 5xx) и запрос можно повторить.
+// Если источник ответил без ошибки 5xx, выключатель замыкается.
+func (c apiClient) attempt(ctx context.Context, rawURL string, out interface{}) (temporary bool, err error) {
+	attemptCtx := ctx
+	if c.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.policy.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Адрес запроса в ошибке содержит ключ API, а ошибка попадает в логи и в очередь
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = c.baseURL
+		}
+		if ctx.Err() != nil {
+			// Запрос отменил вызывающий, источник здесь ни при чем
+			return false, err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	c.limiter.update(resp)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, fmt.Errorf("API request failed with status: %s", resp.Status)
+	}
+	c.breaker.record(true)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return false, c.limiter.exceeded()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("API request failed with status: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		// Ответ оборвался по таймауту попытки
+		return attemptCtx.Err() != nil && ctx.Err() == nil, err
+	}
+	return false, nil
+}
+
+// Agify определяет наиболее вероятный возраст по имени.
+type Agify struct {
+	apiClient
+}
+
+// NewAgify создает источник возраста, обращающийся к baseURL.
+func NewAgify(baseURL string, client *http.Client, opts ...Option) *Agify {
+	return &Agify{apiClient: newAPIClient("agify", baseURL, client, opts)}
+}
+
+func (a *Agify) Field() string { return types.FieldAge }
+
+func (a *Agify) Enrich(ctx context.Context, person *types.Person) error {
+	var data types.AgifyResponse
+	if err := a.get(ctx, person.Name, &data); err != nil {
+		return err
+	}
+	return a.apply(person, data)
+}
+
+func (a *Agify) EnrichBatch(ctx context.Context, people []*types.Person) []error {
+	return a.enrichBatch(ctx, people, func(person *types.Person, raw json.RawMessage) error {
+		var data types.AgifyResponse
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return err
+		}
+		return a.apply(person, data)
+	})
+}
+
+// apply переносит ответ API в person.
+func (a *Agify) apply(person *types.Person, data types.AgifyResponse) error {
+	// Проверка наличия возраста и корректного имени
+	if data.Count == 0 {
+		return fmt.Errorf("Name is incorrect")
+	}
+
+	person.Age = data.Age
+	person.AgeCount = data.Count
+	return nil
+}
+
+// Genderize определяет наиболее вероятный пол по имени.
+type Genderize struct {
+	apiClient
+}
+
+// NewGenderize создает источник пола, обращающийся к baseURL.
+func NewGenderize(baseURL string, client *http.Client, opts ...Option) *Genderize {
+	return &Genderize{apiClient: newAPIClient("genderize", baseURL, client, opts)}
+}
+
+func (g *Genderize) Field() string { return types.FieldGender }
+
+func (g *Genderize) Enrich(ctx context.Context, person *types.Person) error {
+	var data types.GenderizeResponse
+	if err := g.get(ctx, person.Name, &data); err != nil {
+		return err
+	}
+	return g.apply(person, data)
+}
+
+func (g *Genderize) EnrichBatch(ctx context.Context, people []*types.Person) []error {
+	return g.enrichBatch(ctx, people, func(person *types.Person, raw json.RawMessage) error {
+		var data types.GenderizeResponse
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return err
+		}
+		return g.apply(person, data)
+	})
+}
+
+// apply переносит ответ API в person.
+func (g *Genderize) apply(person *types.Person, data types.GenderizeResponse) error {
+
+	if data.Count == 0 {
+		return fmt.Errorf("Name is incorrect")
+	}
+
+	person.Gender = data.Gender
+	person.GenderProbability = data.Probability
+	return nil
+}
+
+// Nationalize определяет наиболее вероятную национальность по имени.
+type Nationalize struct {
+	apiClient
+}
+
+// NewNationalize создает источник национальности, обращающийся к baseURL.
+func NewNationalize(baseURL string, client *http.Client, opts ...Option) *Nationalize {
+	return &Nationalize{apiClient: newAPIClient("nationalize", baseURL, client, opts)}
+}
+
+func (n *Nationalize) Field() string { return types.FieldNationality }
+
+func (n *Nationalize) Enrich(ctx context.Context, person *types.Person) error {
+	var data types.NationalizeResponse
+	if err := n.get(ctx, person.Name, &data); err != nil {
+		return err
+	}
+	return n.apply(person, data)
+}
+
+func (n *Nationalize) EnrichBatch(ctx context.Context, people []*types.Person) []error {
+	return n.enrichBatch(ctx, people, func(person *types.Person, raw json.RawMessage) error {
+		var data types.NationalizeResponse
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return err
+		}
+		return n.apply(person, data)
+	})
+}
+
+// apply переносит ответ API в person.
+func (n *Nationalize) apply(person *types.Person, data types.NationalizeResponse) error {
+
+	if data.Count == 0 {
+		return fmt.Errorf("Name is incorrect")
+	}
+
+	// Сохраняем весь список стран по убыванию вероятности,
+	// в качестве национальности берем наиболее вероятную
+	countries := append([]types.Nationality(nil), data.Country...)
+	sort.SliceStable(countries, func(i, j int) bool {
+		return countries[i].Probability > countries[j].Probability
+	})
+
+	person.Nationalities = countries
+	if len(countries) > 0 {
+		person.Nationality = countries[0].CountryID
+	}
+	return nil
+}
+
+// Run параллельно запускает источники для еще не обогащенных полей person.
+// Каждый источник заполняет только свои поля, поэтому гонок между ними нет.
+// Поля, обогащенные успешно, получают статус complete независимо от остальных:
+// недоступность одного источника не мешает сохранить результаты других.
+// Возвращает ошибку, если хотя бы один источник завершился неудачно;
+// если квота источника исчерпана, он не опрашивается и ошибка содержит *QuotaError.
+func Run(ctx context.Context, person *types.Person, enrichers []Enricher) error {
+	errs := make([]error, len(enrichers))
+	enriched := make([]bool, len(enrichers))
+
+	var wg sync.WaitGroup
+	for i, e := range enrichers {
+		if person.FieldEnriched(e.Field()) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, e Enricher) {
+			defer wg.Done()
+			if err := e.Enrich(ctx, person); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", e.Name(), err)
+				return
+			}
+			enriched[i] = true
+		}(i, e)
+	}
+	wg.Wait()
+
+	// Статусы полей записываются после завершения источников: карта не потокобезопасна
+	for i, e := range enrichers {
+		if enriched[i] {
+			person.SetFieldStatus(e.Field(), types.EnrichmentComplete)
+		}
+	}
+
+	return combineErrors(errs)
+}
+
+// ShouldDefer сообщает, что запись, которую не удалось обогатить синхронно
+// с ошибкой err, нужно сохранить со статусом pending, чтобы оставшиеся поля
+// обогатил пул воркеров: квота внешних API исчерпана или часть полей уже обогащена.
+// Иначе (err == nil или ни одно поле не обогащено) запись не откладывается.
+func ShouldDefer(err error, person *types.Person) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrQuotaExceeded) || person.EnrichedCount() > 0
+}
+
+// combineErrors объединяет ошибки источников в одну; nil, если ошибок нет.
+func combineErrors(errs []error) error {
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return &enrichmentError{errs: failed}
+	}
+
+	return nil
+}
+
+// enrichmentError - ошибки нескольких источников. errors.Is и errors.As
+// проверяют каждую из них, например, чтобы найти *QuotaError.
+type enrichmentError struct {
+	errs []error
+}
+
+func (e *enrichmentError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+	return "enrichment failed: " + strings.Join(messages, "; ")
+}
+
+func (e *enrichmentError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *enrichmentError) As(target interface{}) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "Dmitriy" {
+			t.Errorf("unexpected name: %q", r.URL.Query().Get("name"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun(t *testing.T) {
+	agify := stubServer(t, `{"count": 10, "name": "Dmitriy", "age": 42}`)
+	genderize := stubServer(t, `{"count": 10, "name": "Dmitriy", "gender": "male", "probability": 1}`)
+	nationalize := stubServer(t, `{"count": 10, "name": "Dmitriy", "country": [{"country_id": "UA", "probability": 0.4}, {"country_id": "RU", "probability": 0.6}]}`)
+
+	enrichers := []Enricher{
+		NewAgify(agify.URL, agify.Client()),
+		NewGenderize(genderize.URL, genderize.Client()),
+		NewNationalize(nationalize.URL, nationalize.Client()),
+	}
+
+	person := &types.Person{Name: "Dmitriy", Surname: "Ushakov"}
+	if err := Run(context.Background(), person, enrichers); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if person.Age != 42 || person.Gender != "male" || person.Nationality != "RU" {
+		t.Fatalf("Unexpected enrichment result: %+v", person)
+	}
+
+	if person.AgeCount != 10 || person.GenderProbability != 1 {
+		t.Fatalf("Confidence values were not kept: %+v", person)
+	}
+
+	if len(person.Nationalities) != 2 || person.Nationalities[1].CountryID != "UA" {
+		t.Fatalf("Nationalities are not ranked: %+v", person.Nationalities)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	agify := stubServer(t, `{"count": 10, "name": "Dmitriy", "age": 42}`)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	enrichers := []Enricher{
+		NewAgify(agify.URL, agify.Client()),
+		NewGenderize(failing.URL, failing.Client()),
+	}
+
+	person := &types.Person{Name: "Dmitriy"}
+	if err := Run(context.Background(), person, enrichers); err == nil {
+		t.Fatal("Expected error from failing enricher")
+	}
+
+	// Недоступность genderize не мешает обогатить возраст
+	if person.Age != 42 || !person.FieldEnriched(types.FieldAge) || person.FieldEnriched(types.FieldGender) {
+		t.Fatalf("Expected age to be enriched independently: %+v", person)
+	}
+}
+
+func TestRunMissingFields(t *testing.T) {
+	var requests int32
+	agify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"count": 10, "name": "Dmitriy", "age": 30}`)
+	}))
+	defer agify.Close()
+	genderize := stubServer(t, `{"count": 10, "name": "Dmitriy", "gender": "male", "probability": 1}`)
+
+	enrichers := []Enricher{
+		NewAgify(agify.URL, agify.Client()),
+		NewGenderize(genderize.URL, genderize.Client()),
+	}
+
+	// Возраст уже обогащен, повторно запрашивается только пол
+	person := &types.Person{Name: "Dmitriy", Age: 42, EnrichmentFields: map[string]string{
+		types.FieldAge: types.EnrichmentComplete, types.FieldGender: types.EnrichmentFailed,
+	}}
+	if err := Run(context.Background(), person, enrichers); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if requests != 0 || person.Age != 42 {
+		t.Fatalf("Expected enriched age to be kept, got %d requests, age %d", requests, person.Age)
+	}
+	if person.Gender != "male" || !person.FieldEnriched(types.FieldGender) {
+		t.Fatalf("Expected gender to be enriched: %+v", person)
+	}
+}
+
+func TestRunUnknownName(t *testing.T) {
+	server := stubServer(t, `{"count": 0, "name": "Dmitriy", "age": null}`)
+
+	person := &types.Person{Name: "Dmitriy"}
+	if err := Run(context.Background(), person, []Enricher{NewAgify(server.URL, server.Client())}); err == nil {
+		t.Fatal("Expected error for unknown name")
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	var requests int32
+	agify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var results []string
+		for _, name := range r.URL.Query()["name[]"] {
+			count := 10
+			if name == "Unknown" {
+				count = 0
+			}
+			results = append(results, fmt.Sprintf(`{"count": %d, "name": %q, "age": %d}`, count, name, len(name)))
+		}
+		fmt.Fprint(w, "["+strings.Join(results, ",")+"]")
+	}))
+	defer agify.Close()
+
+	people := []*types.Person{{Name: "Oleg"}, {Name: "Dmitriy"}, {Name: "oleg"}, {Name: "Unknown"}}
+	for i := 0; i < MaxBatchNames; i++ {
+		people = append(people, &types.Person{Name: fmt.Sprintf("Name%d", i)})
+	}
+
+	errs := RunBatch(context.Background(), people, []Enricher{NewAgify(agify.URL, agify.Client())})
+
+	// 13 уникальных имен - два запроса по MaxBatchNames
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+	if errs[0] != nil || errs[2] != nil || errs[3] == nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if !people[0].FieldEnriched(types.FieldAge) || people[3].FieldEnriched(types.FieldAge) {
+		t.Fatalf("Unexpected field statuses: %v, %v", people[0].EnrichmentFields, people[3].EnrichmentFields)
+	}
+	if people[0].Age != 4 || people[2].Age != 4 || people[1].Age != 7 || people[len(people)-1].Age != 5 {
+		t.Fatalf("Unexpected ages: %d, %d, %d", people[0].Age, people[1].Age, people[2].Age)
+	}
+}
+
+func TestShouldDefer(t *testing.T) {
+	unenriched := &types.Person{Name: "Dmitriy"}
+	partial := &types.Person{Name: "Dmitriy"}
+	partial.SetFieldStatus(types.FieldAge, types.EnrichmentComplete)
+
+	failed := combineErrors([]error{errors.New("genderize: unavailable")})
+	quota := combineErrors([]error{&QuotaError{Provider: "agify", Reset: time.Now()}})
+
+	tests := []struct {
+		err    error
+		person *types.Person
+		want   bool
+	}{
+		{nil, partial, false},
+		{failed, unenriched, false},
+		{failed, partial, true},
+		{quota, unenriched, true},
+	}
+	for i, tt := range tests {
+		if got := ShouldDefer(tt.err, tt.person); got != tt.want {
+			t.Errorf("Case %d: expected %v, got %v", i, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package enrich
+
+import (
+	"errors"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Заголовки, в которых API сообщают о дневной квоте запросов.
+const (
+	headerRateLimit     = "X-Rate-Limit-Limit"
+	headerRateRemaining = "X-Rate-Limit-Remaining"
+	headerRateReset     = "X-Rate-Limit-Reset" // секунд до восстановления квоты
+)
+
+// ErrQuotaExceeded означает, что квота запросов источника исчерпана.
+// Конкретная ошибка - *QuotaError со временем восстановления квоты.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaError - квота запросов источника исчерпана до Reset.
+type QuotaError struct {
+	Provider string
+	Reset    time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded until %s", e.Provider, e.Reset.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// QuotaReporter - источник, который отслеживает свою квоту запросов.
+type QuotaReporter interface {
+	Quota() types.ProviderQuota
+}
+
+// limiter отслеживает квоту источника по заголовкам ответов и не отправляет
+// запросы, пока квота исчерпана. До первого ответа квота неизвестна и не ограничивает запросы.
+type limiter struct {
+	provider string
+
+	mu        sync.Mutex
+	known     bool
+	limit     int
+	remaining int
+	reset     time.Time
+
+	now func() time.Time
+}
+
+func newLimiter(provider string) *limiter {
+	return &limiter{provider: provider, now: time.Now}
+}
+
+// reserve резервирует квоту на запрос по names именам или возвращает *QuotaError.
+// Ответ API уточнит остаток через update.
+func (l *limiter) reserve(names int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.known && !l.now().Before(l.reset) {
+		// Квота восстановилась, остаток станет известен из следующего ответа
+		l.known = false
+	}
+	if l.known && l.remaining < names {
+		return &QuotaError{Provider: l.provider, Reset: l.reset}
+	}
+	if l.known {
+		l.remaining -= names
+	}
+	return nil
+}
+
+// update запоминает квоту из заголовков ответа. Ответ 429 означает, что квота
+// исчерпана, даже если заголовков нет; тогда она восстанавливается в полночь UTC.
+func (l *limiter) update(resp *http.Response) {
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get(headerRateRemaining))
+	resetIn, resetErr := strconv.Atoi(resp.Header.Get(headerRateReset))
+	exceeded := resp.StatusCode == http.StatusTooManyRequests
+	if remainingErr != nil && !exceeded {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.known = true
+	l.remaining = remaining
+	if exceeded {
+		l.remaining = 0
+	}
+	if limit, err := strconv.Atoi(resp.Header.Get(headerRateLimit)); err == nil {
+		l.limit = limit
+	}
+	if resetErr == nil {
+		l.reset = now.Add(time.Duration(resetIn) * time.Second)
+	} else {
+		l.reset = now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	}
+}
+
+// exceeded возвращает ошибку исчерпанной квоты после ответа 429.
+func (l *limiter) exceeded() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &QuotaError{Provider: l.provider, Reset: l.reset}
+}
+
+// status возвращает известное состояние квоты.
+func (l *limiter) status() types.ProviderQuota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	quota := types.ProviderQuota{Provider: l.provider}
+	if !l.known || !l.now().Before(l.reset) {
+		return quota
+	}
+
+	limit, remaining, reset := l.limit, l.remaining, l.reset
+	if limit > 0 {
+		quota.Limit = &limit
+	}
+	quota.Remaining = &remaining
+	quota.Reset = &reset
+	quota.Exhausted = remaining <= 0
+	return quota
+}
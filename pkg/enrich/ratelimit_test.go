@@ -0,0 +1,113 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuotaHeaders(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "secret" {
+			t.Errorf("unexpected apikey: %q", r.URL.Query().Get("apikey"))
+		}
+		remaining := 1 - atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Rate-Limit-Limit", "1000")
+		w.Header().Set("X-Rate-Limit-Remaining", fmt.Sprint(remaining))
+		w.Header().Set("X-Rate-Limit-Reset", "3600")
+		fmt.Fprint(w, `{"count": 10, "name": "Dmitriy", "age": 42}`)
+	}))
+	defer server.Close()
+
+	agify := NewAgify(server.URL, server.Client(), WithAPIKey("secret"))
+	if quota := agify.Quota(); quota.Remaining != nil || quota.Exhausted {
+		t.Fatalf("Expected unknown quota before first request, got %+v", quota)
+	}
+
+	person := &types.Person{Name: "Dmitriy"}
+	if err := Run(context.Background(), person, []Enricher{agify}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	quota := agify.Quota()
+	if quota.Limit == nil || *quota.Limit != 1000 || quota.Remaining == nil || *quota.Remaining != 0 || !quota.Exhausted {
+		t.Fatalf("Unexpected quota: %+v", quota)
+	}
+	if quota.Reset == nil || time.Until(*quota.Reset) < 59*time.Minute {
+		t.Fatalf("Unexpected quota reset: %v", quota.Reset)
+	}
+
+	// Квота исчерпана: запрос не отправляется
+	err := Run(context.Background(), &types.Person{Name: "Dmitriy"}, []Enricher{agify})
+	var quotaErr *QuotaError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &quotaErr) || quotaErr.Provider != "agify" {
+		t.Fatalf("Expected quota error, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	var requests int32
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error": "Request limit reached"}`)
+	}))
+	defer limited.Close()
+	agify := stubServer(t, `{"count": 10, "name": "Dmitriy", "age": 42}`)
+
+	genderize := NewGenderize(limited.URL, limited.Client())
+	enrichers := []Enricher{NewAgify(agify.URL, agify.Client()), genderize}
+
+	err := Run(context.Background(), &types.Person{Name: "Dmitriy"}, enrichers)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected quota error, got %v", err)
+	}
+
+	// Без заголовка X-Rate-Limit-Reset квота восстанавливается в полночь UTC
+	quota := genderize.Quota()
+	if !quota.Exhausted || quota.Reset == nil || quota.Reset.UTC().Hour() != 0 || !quota.Reset.After(time.Now()) {
+		t.Fatalf("Unexpected quota: %+v", quota)
+	}
+
+	// Пакетное обогащение не опрашивает источник, пока его квота исчерпана
+	errs := RunBatch(context.Background(), []*types.Person{{Name: "Dmitriy"}}, []Enricher{genderize})
+	if !errors.Is(errs[0], ErrQuotaExceeded) || requests != 1 {
+		t.Fatalf("Expected quota error without requests, got %v after %d requests", errs[0], requests)
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	l := newLimiter("agify")
+	l.now = func() time.Time { return now }
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-Rate-Limit-Remaining", "2")
+	resp.Header.Set("X-Rate-Limit-Reset", "60")
+	l.update(resp)
+
+	if err := l.reserve(3); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected quota error for 3 names, got %v", err)
+	}
+	if err := l.reserve(2); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if err := l.reserve(1); err == nil {
+		t.Fatal("Expected quota error after reservation")
+	}
+
+	now = now.Add(time.Minute)
+	if err := l.reserve(1); err != nil {
+		t.Fatalf("Expected quota to be reset, got %v", err)
+	}
+}
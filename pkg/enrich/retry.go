@@ -0,0 +1,125 @@
+package enrich
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen означает, что источник недавно отвечал ошибками подряд
+// и запросы к нему временно не отправляются.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Policy - настройки надежности запросов к источнику.
+type Policy struct {
+	Timeout time.Duration // ограничение одной попытки запроса; 0 - без ограничения
+
+	// Повторы после сетевых ошибок и ответов 5xx: задержка перед n-м повтором -
+	// случайная величина от половины до целого RetryDelay * 2^(n-1), но не больше MaxRetryDelay.
+	MaxRetries    int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	// После BreakerThreshold неудачных запросов подряд (неудачей считается запрос,
+	// все повторы которого завершились ошибкой) запросы не отправляются
+	// в течение BreakerCooldown, затем пропускается один пробный запрос.
+	// 0 - автоматический выключатель не используется.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultPolicy используется источниками, для которых Policy не задана.
+var DefaultPolicy = Policy{
+	Timeout:          10 * time.Second,
+	MaxRetries:       2,
+	RetryDelay:       200 * time.Millisecond,
+	MaxRetryDelay:    2 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// backoff возвращает задержку перед повтором номер retry (с 1).
+func (p Policy) backoff(retry int) time.Duration {
+	delay := p.RetryDelay
+	for i := 1; i < retry && delay < p.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxRetryDelay > 0 && delay > p.MaxRetryDelay {
+		delay = p.MaxRetryDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Случайная составляющая разводит во времени повторы одновременных запросов
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// breaker - автоматический выключатель: после threshold неудач подряд размыкается
+// на cooldown, затем пропускает один пробный запрос и замыкается, если он успешен.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+
+	now func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow разрешает запрос или возвращает ErrCircuitOpen.
+func (b *breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record учитывает результат запроса.
+func (b *breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+}
+
+// cancel завершает запрос, не считая его ни успешным, ни неудачным
+// (отмена контекста, исчерпанная квота или уже учтенный успешный ответ).
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
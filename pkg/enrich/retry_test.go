@@ -0,0 +1,177 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"junior-test/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPolicy - повторы без заметных задержек и без выключателя.
+var testPolicy = Policy{Timeout: time.Second, MaxRetries: 2, RetryDelay: time.Millisecond, MaxRetryDelay: 5 * time.Millisecond}
+
+// flakyServer отвечает статусом statuses[i] на i-й запрос, затем - успешно.
+func flakyServer(t *testing.T, requests *int32, statuses ...int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(requests, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		fmt.Fprint(w, `{"count": 10, "name": "Dmitriy", "age": 42}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	var requests int32
+	server := flakyServer(t, &requests, http.StatusBadGateway, http.StatusServiceUnavailable)
+
+	person := &types.Person{Name: "Dmitriy"}
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(testPolicy))
+	if err := agify.Enrich(context.Background(), person); err != nil || person.Age != 42 {
+		t.Fatalf("Expected success after retries, got %+v, %v", person, err)
+	}
+	if requests != 3 {
+		t.Fatalf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestNoRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := flakyServer(t, &requests, http.StatusUnprocessableEntity)
+
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(testPolicy))
+	if err := agify.Enrich(context.Background(), &types.Person{Name: "Dmitriy"}); err == nil {
+		t.Fatal("Expected error for 422 response")
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Зависший источник: не отвечает, пока клиент не прервет запрос
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	policy := testPolicy
+	policy.Timeout = 50 * time.Millisecond
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(policy))
+
+	start := time.Now()
+	err := agify.Enrich(context.Background(), &types.Person{Name: "Dmitriy"})
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected attempts to time out quickly, took %s", elapsed)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests int32
+	server := flakyServer(t, &requests, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	policy := testPolicy
+	policy.MaxRetries = 0
+	policy.BreakerThreshold = 2
+	policy.BreakerCooldown = time.Minute
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(policy))
+
+	now := time.Now()
+	agify.breaker.now = func() time.Time { return now }
+
+	person := &types.Person{Name: "Dmitriy"}
+	for i := 0; i < 2; i++ {
+		if err := agify.Enrich(context.Background(), person); err == nil {
+			t.Fatal("Expected error from failing server")
+		}
+	}
+
+	// Выключатель разомкнут: запрос не отправляется
+	if err := agify.Enrich(context.Background(), person); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+
+	// После паузы пробный запрос успешен, и выключатель замыкается
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := agify.Enrich(context.Background(), person); err != nil {
+			t.Fatalf("Expected success after cooldown, got %v", err)
+		}
+	}
+	if requests != 4 {
+		t.Fatalf("Expected 4 requests, got %d", requests)
+	}
+}
+
+func TestCircuitBreakerCountsCalls(t *testing.T) {
+	var requests int32
+	server := flakyServer(t, &requests, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	policy := testPolicy
+	policy.BreakerThreshold = 2
+	policy.BreakerCooldown = time.Minute
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(policy))
+
+	// Три неудачные попытки одного запроса - одна неудача выключателя
+	person := &types.Person{Name: "Dmitriy"}
+	if err := agify.Enrich(context.Background(), person); err == nil {
+		t.Fatal("Expected error after retries are exhausted")
+	}
+	if err := agify.Enrich(context.Background(), person); err != nil {
+		t.Fatalf("Expected breaker to stay closed, got %v", err)
+	}
+	if requests != 4 {
+		t.Fatalf("Expected 4 requests, got %d", requests)
+	}
+}
+
+func TestRetryReservesQuota(t *testing.T) {
+	var requests int32
+	server := flakyServer(t, &requests, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	agify := NewAgify(server.URL, server.Client(), WithPolicy(testPolicy))
+	agify.limiter.known, agify.limiter.remaining, agify.limiter.reset = true, 1, time.Now().Add(time.Hour)
+
+	// Квоты хватает только на первую попытку, повтор не отправляется
+	err := agify.Enrich(context.Background(), &types.Person{Name: "Dmitriy"})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{RetryDelay: 100 * time.Millisecond, MaxRetryDelay: 300 * time.Millisecond}
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 10: 300 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if delay := policy.backoff(retry); delay < max/2 || delay > max {
+				t.Fatalf("Retry %d: expected delay in [%s, %s], got %s", retry, max/2, max, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+	"junior-test/pkg/types"
+	"strings"
+)
+
+// Column - столбец выгрузки: имя в заголовке и значение поля записи.
+// Значение - nil, string, int, float64 или time.Time.
+type Column struct {
+	Name  string
+	Value func(p *types.Person) interface{}
+}
+
+// Columns - все столбцы, доступные для выгрузки, в порядке по умолчанию.
+var Columns = []Column{
+	{"id", func(p *types.Person) interface{} { return p.ID }},
+	{"name", func(p *types.Person) interface{} { return p.Name }},
+	{"surname", func(p *types.Person) interface{} { return p.Surname }},
+	{"patronymic", func(p *types.Person) interface{} { return p.Patronymic }},
+	{"age", func(p *types.Person) interface{} { return p.Age }},
+	{"age_count", func(p *types.Person) interface{} { return p.AgeCount }},
+	{"gender", func(p *types.Person) interface{} { return p.Gender }},
+	{"gender_probability", func(p *types.Person) interface{} { return p.GenderProbability }},
+	{"nationality", func(p *types.Person) interface{} { return p.Nationality }},
+	{"enrichment_status", func(p *types.Person) interface{} { return p.EnrichmentStatus }},
+	{"created_at", func(p *types.Person) interface{} { return p.CreatedAt }},
+	{"updated_at", func(p *types.Person) interface{} { return p.UpdatedAt }},
+	{"version", func(p *types.Person) interface{} { return p.Version }},
+	{"deleted_at", func(p *types.Person) interface{} {
+		if p.DeletedAt == nil {
+			return nil
+		}
+		return *p.DeletedAt
+	}},
+	{"score", func(p *types.Person) interface{} {
+		if p.Score == nil {
+			return nil
+		}
+		return *p.Score
+	}},
+}
+
+// DefaultColumns - столбцы, выгружаемые, если список не задан.
+var DefaultColumns = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality", "enrichment_status", "created_at", "updated_at"}
+
+// ParseColumns возвращает столбцы по списку имен (пустой список - DefaultColumns).
+// Неизвестные и повторяющиеся имена - ошибка.
+func ParseColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+
+	byName := make(map[string]Column, len(Columns))
+	for _, column := range Columns {
+		byName[column.Name] = column
+	}
+
+	columns := make([]Column, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s'", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column '%s'", name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
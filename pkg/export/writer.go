@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"junior-test/pkg/types"
+	"strconv"
+	"time"
+)
+
+// Форматы выгрузки.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// ValidFormat проверяет, что формат поддерживается.
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatNDJSON || format == FormatXLSX
+}
+
+// ContentType возвращает MIME-тип формата.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Writer последовательно записывает людей в выходной поток.
+// Close дописывает буферизованные данные и завершает файл, но не закрывает поток.
+type Writer interface {
+	Write(p *types.Person) error
+	Close() error
+}
+
+// NewWriter создает Writer для формата format. Для CSV и XLSX сразу пишется заголовок.
+func NewWriter(w io.Writer, format string, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported format '%s'", format)
+}
+
+// formatValue приводит значение столбца к строке: nil - пустая строка,
+// время - RFC 3339, как в JSON-ответах API.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		cw.record[i] = column.Name
+	}
+	return cw, cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Write(p *types.Person) error {
+	for i, column := range cw.columns {
+		cw.record[i] = formatValue(column.Value(p))
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter пишет каждую запись отдельным JSON-объектом, поля - в порядке столбцов.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []Column
+}
+
+func (nw *ndjsonWriter) Write(p *types.Person) error {
+	nw.w.WriteByte('{')
+	for i, column := range nw.columns {
+		if i > 0 {
+			nw.w.WriteByte(',')
+		}
+		value, err := json.Marshal(column.Value(p))
+		if err != nil {
+			return err
+		}
+		name, _ := json.Marshal(column.Name)
+		nw.w.Write(name)
+		nw.w.WriteByte(':')
+		nw.w.Write(value)
+	}
+	_, err := nw.w.WriteString("}\n")
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"junior-test/pkg/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportAll записывает людей в формате format и возвращает результат.
+func exportAll(t *testing.T, format string, names []string, people ...*types.Person) []byte {
+	t.Helper()
+	columns, err := ParseColumns(names)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, columns)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for _, p := range people {
+		if err := w.Write(p); err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	return buf.Bytes()
+}
+
+var testPeople = []*types.Person{
+	{ID: 1, Name: "Oleg", Surname: "Samsonov", Age: 29, Nationality: "RU", CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+	{ID: 2, Name: "Katerina", Surname: "O'Brien, \"Kate\"", GenderProbability: 0.75},
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns(nil)
+	if err != nil || len(columns) != len(DefaultColumns) {
+		t.Fatalf("Expected default columns, got %d, %v", len(columns), err)
+	}
+
+	if _, err := ParseColumns([]string{"id", "password"}); err == nil {
+		t.Fatalf("Expected error for unknown column")
+	}
+	if _, err := ParseColumns([]string{"id", "id"}); err == nil {
+		t.Fatalf("Expected error for duplicate column")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	out := exportAll(t, FormatCSV, []string{"id", "surname", "age", "gender_probability", "created_at", "deleted_at"}, testPeople...)
+
+	expected := "id,surname,age,gender_probability,created_at,deleted_at\n" +
+		"1,Samsonov,29,0,2024-05-01T10:00:00Z,\n" +
+		"2,\"O'Brien, \"\"Kate\"\"\",0,0.75,0001-01-01T00:00:00Z,\n"
+	if string(out) != expected {
+		t.Fatalf("Unexpected CSV:\n%s", out)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	out := exportAll(t, FormatNDJSON, []string{"surname", "id", "deleted_at"}, testPeople...)
+
+	expected := `{"surname":"Samsonov","id":1,"deleted_at":null}` + "\n" +
+		`{"surname":"O'Brien, \"Kate\"","id":2,"deleted_at":null}` + "\n"
+	if string(out) != expected {
+		t.Fatalf("Unexpected NDJSON:\n%s", out)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := exportAll(t, FormatXLSX, []string{"id", "name", "surname", "patronymic"}, testPeople...)
+
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("Expected valid ZIP archive, but got %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("Expected part %s in archive", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>id</t></is></c>`,
+		`<row r="2"><c r="A2"><v>1</v></c><c r="B2" t="inlineStr"><is><t>Oleg</t></is></c>`,
+		`<c r="C3" t="inlineStr"><is><t>O&#39;Brien, &#34;Kate&#34;</t></is></c>`,
+		`</row></sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Fatalf("Expected sheet to contain %s, got:\n%s", expected, sheet)
+		}
+	}
+}
+
+func TestColumnRef(t *testing.T) {
+	for index, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if ref := columnRef(index); ref != expected {
+			t.Fatalf("Expected %s for %d, got %s", expected, index, ref)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"junior-test/pkg/types"
+	"strconv"
+	"strings"
+)
+
+// MaxXLSXRows - максимальное количество строк листа XLSX, включая заголовок.
+const MaxXLSXRows = 1048576
+
+// ErrTooManyRows означает, что записи не помещаются на лист XLSX.
+var ErrTooManyRows = errors.New("too many rows for an XLSX sheet")
+
+// Неизменяемые части книги XLSX (Office Open XML) с единственным листом.
+// Лист содержит только inline-строки и числа, поэтому sharedStrings.xml и styles.xml не нужны.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="people" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter пишет книгу XLSX потоково: архив ZIP формируется по мере записи,
+// строки листа не накапливаются в памяти.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+	refs    []string // буквенные обозначения столбцов: A, B, ...
+	rows    int
+}
+
+func newXLSXWriter(w io.Writer, columns []Column) (*xlsxWriter, error) {
+	xw := &xlsxWriter{zip: zip.NewWriter(w), columns: columns, refs: make([]string, len(columns))}
+	for i := range columns {
+		xw.refs[i] = columnRef(i)
+	}
+
+	for _, part := range xlsxParts {
+		f, err := xw.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := xw.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = bufio.NewWriter(sheet)
+	xw.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return xw, xw.writeRow(header)
+}
+
+func (xw *xlsxWriter) Write(p *types.Person) error {
+	values := make([]interface{}, len(xw.columns))
+	for i, column := range xw.columns {
+		values[i] = column.Value(p)
+	}
+	return xw.writeRow(values)
+}
+
+func (xw *xlsxWriter) writeRow(values []interface{}) error {
+	if xw.rows == MaxXLSXRows {
+		return ErrTooManyRows
+	}
+	xw.rows++
+	row := strconv.Itoa(xw.rows)
+
+	w := xw.sheet
+	w.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		ref := xw.refs[i] + row
+		switch v := value.(type) {
+		case nil:
+			// Пустая ячейка не записывается
+		case int:
+			w.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(v) + `</v></c>`)
+		case float64:
+			w.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+		default:
+			text := formatValue(v)
+			w.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t`)
+			if strings.TrimSpace(text) != text {
+				w.WriteString(` xml:space="preserve"`)
+			}
+			w.WriteByte('>')
+			if err := xml.EscapeText(w, []byte(text)); err != nil {
+				return err
+			}
+			w.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := w.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// columnRef возвращает буквенное обозначение столбца по индексу: 0 - A, 25 - Z, 26 - AA.
+func columnRef(index int) string {
+	ref := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		ref = string(rune('A'+(index-1)%26)) + ref
+	}
+	return ref
+}
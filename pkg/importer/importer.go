@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	db "junior-test/db/models"
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/types"
+	"log"
+	"sort"
+)
+
+// Режимы обогащения импортируемых записей.
+const (
+	EnrichQueue = "queue" // записи сохраняются со статусом pending, обогащение выполнит пул воркеров
+	EnrichSync  = "sync"  // записи обогащаются перед сохранением, необогащенные отклоняются (при исчерпанной квоте API - ставятся в очередь)
+	EnrichNone  = "none"  // записи сохраняются как есть, вместе с переданными возрастом, полом и национальностью; остальные поля - со статусом failed
+)
+
+// DefaultBatchSize - количество записей, сохраняемых одним запросом, по умолчанию.
+const DefaultBatchSize = 100
+
+// ValidEnrichMode проверяет режим обогащения.
+func ValidEnrichMode(mode string) bool {
+	return mode == EnrichQueue || mode == EnrichSync || mode == EnrichNone
+}
+
+// Importer загружает людей из CSV или NDJSON пачками через репозиторий.
+type Importer struct {
+	Repository *db.SQLPersonRepository
+	Enrichers  []enrich.Enricher // используются в режиме EnrichSync
+
+	Enrich    string // режим обогащения, по умолчанию EnrichQueue
+	BatchSize int    // по умолчанию DefaultBatchSize
+	Actor     string // автор записей в истории изменений
+}
+
+// Run потоково читает записи из r в формате format, проверяет каждую и сохраняет
+// пачками по BatchSize. Отклоненные строки попадают в отчет с номерами строк.
+// Ошибка возвращается, если данные нельзя дочитать или пачку не удалось сохранить;
+// отчет при этом описывает уже обработанную часть данных.
+func (im *Importer) Run(ctx context.Context, r io.Reader, format string) (*types.ImportReport, error) {
+	report := &types.ImportReport{Rejected: []types.ImportRejection{}}
+	// В режиме EnrichSync строки отклоняются и после проверки - при обогащении пачки
+	defer func() {
+		sort.SliceStable(report.Rejected, func(i, j int) bool { return report.Rejected[i].Line < report.Rejected[j].Line })
+	}()
+
+	mode := im.Enrich
+	if mode == "" {
+		mode = EnrichQueue
+	}
+	if !ValidEnrichMode(mode) {
+		return report, fmt.Errorf("unknown enrichment mode %q, expected queue, sync or none", mode)
+	}
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	rows, err := newRowReader(r, format)
+	if err != nil {
+		return report, err
+	}
+
+	var batch []*types.Person
+	var lines []int
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.Total++
+		if row.err == nil {
+			row.err = validate(&row.person, row.ageSet, mode)
+		}
+		if row.err != nil {
+			report.Reject(row.line, row.err)
+			continue
+		}
+
+		person := row.person
+		batch = append(batch, &person)
+		lines = append(lines, row.line)
+
+		if len(batch) >= batchSize {
+			if err := im.flush(ctx, mode, batch, lines, report); err != nil {
+				return report, err
+			}
+			batch, lines = nil, nil
+		}
+	}
+
+	if err := im.flush(ctx, mode, batch, lines, report); err != nil {
+		return report, err
+	}
+
+	log.Printf("Imported %d of %d people, %d rejected", report.Imported, report.Total, len(report.Rejected))
+	return report, nil
+}
+
+// validate проверяет запись; переданные поля обогащения сохраняются только в режиме EnrichNone.
+// ageSet означает, что возраст передан явно.
+func validate(person *types.Person, ageSet bool, mode string) error {
+	if err := types.ValidatePerson(person); err != nil {
+		return err
+	}
+	if mode == EnrichNone {
+		return types.ValidateEnrichment(person, ageSet)
+	}
+	return nil
+}
+
+// flush обогащает пачку в соответствии с режимом и сохраняет ее одним запросом.
+func (im *Importer) flush(ctx context.Context, mode string, batch []*types.Person, lines []int, report *types.ImportReport) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	ready := batch
+	switch mode {
+	case EnrichSync:
+		ready = nil
+		for i, err := range enrich.RunBatch(ctx, batch, im.Enrichers) {
+			if enrich.ShouldDefer(err, batch[i]) {
+				batch[i].EnrichmentStatus = types.EnrichmentPending
+				err = nil
+			}
+			if err != nil {
+				report.Reject(lines[i], err)
+				continue
+			}
+			ready = append(ready, batch[i])
+		}
+	case EnrichQueue:
+		for _, person := range batch {
+			person.EnrichmentStatus = types.EnrichmentPending
+		}
+	}
+
+	if err := im.Repository.CreatePeople(ready, im.Actor); err != nil {
+		return fmt.Errorf("failed to save people from lines %d-%d: %w", lines[0], lines[len(lines)-1], err)
+	}
+
+	report.Imported += len(ready)
+	return nil
+}
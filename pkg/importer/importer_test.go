@@ -0,0 +1,32 @@
+package importer
+
+import (
+	"junior-test/pkg/types"
+	"testing"
+)
+
+func TestValidateEnrichNone(t *testing.T) {
+	person := types.Person{Name: "Ivan", Surname: "Ivanov", Gender: "male", EnrichmentStatus: types.EnrichmentComplete}
+	if err := validate(&person, false, EnrichNone); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	want := map[string]string{types.FieldAge: types.EnrichmentFailed, types.FieldGender: types.EnrichmentComplete, types.FieldNationality: types.EnrichmentFailed}
+	for field, status := range want {
+		if got := person.EnrichmentFields[field]; got != status {
+			t.Errorf("Field %s: expected status %q, got %q", field, status, got)
+		}
+	}
+	if person.EnrichmentStatus != types.EnrichmentPartial {
+		t.Errorf("Expected status %q, got %q", types.EnrichmentPartial, person.EnrichmentStatus)
+	}
+
+	// Явно переданный возраст 0 - тоже значение
+	person = types.Person{Name: "Ivan", Surname: "Ivanov", Age: 0, Gender: "male", Nationality: "RU"}
+	if err := validate(&person, true, EnrichNone); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if person.EnrichmentStatus != types.EnrichmentComplete {
+		t.Errorf("Expected status %q, got %q", types.EnrichmentComplete, person.EnrichmentStatus)
+	}
+}
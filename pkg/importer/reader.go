@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"junior-test/pkg/types"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Форматы входных данных.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// maxLineSize - максимальная длина строки NDJSON.
+const maxLineSize = 1 << 20
+
+// ErrInvalidInput означает, что входные данные нельзя прочитать целиком
+// (неизвестный формат, некорректный заголовок CSV, слишком длинная строка).
+var ErrInvalidInput = errors.New("invalid input")
+
+// ValidFormat проверяет, что формат поддерживается.
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatNDJSON
+}
+
+// FormatFromName определяет формат по расширению файла; "", если расширение неизвестно.
+func FormatFromName(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// FormatFromContentType определяет формат по Content-Type; "", если тип неизвестен.
+func FormatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// row - прочитанная строка данных: номер строки во входном файле и запись
+// либо причина, по которой строка отклонена.
+type row struct {
+	line   int
+	person types.Person
+	ageSet bool // возраст передан явно, в том числе равный 0
+	err    error
+}
+
+// rowReader последовательно читает строки данных. В конце данных возвращает io.EOF,
+// другие ошибки означают, что продолжить чтение нельзя.
+type rowReader interface {
+	next() (row, error)
+}
+
+func newRowReader(r io.Reader, format string) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q, expected csv or ndjson", ErrInvalidInput, format)
+}
+
+// csvColumns - поддерживаемые столбцы CSV и поля строки, в которые они записываются.
+var csvColumns = map[string]func(r *row, value string) error{
+	"name":        func(r *row, v string) error { r.person.Name = v; return nil },
+	"surname":     func(r *row, v string) error { r.person.Surname = v; return nil },
+	"patronymic":  func(r *row, v string) error { r.person.Patronymic = v; return nil },
+	"gender":      func(r *row, v string) error { r.person.Gender = v; return nil },
+	"nationality": func(r *row, v string) error { r.person.Nationality = v; return nil },
+	"age": func(r *row, v string) error {
+		if v = strings.TrimSpace(v); v == "" {
+			return nil
+		}
+		age, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("Field 'age' must be an integer")
+		}
+		r.person.Age, r.ageSet = age, true
+		return nil
+	},
+}
+
+// csvReader читает CSV с заголовком, задающим порядок столбцов.
+type csvReader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	// Количество полей проверяем сами, чтобы отклонять строку, а не весь файл
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: CSV header is missing", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	// Excel сохраняет CSV в UTF-8 с BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	seen := map[string]bool{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, ok := csvColumns[column]; !ok {
+			return nil, fmt.Errorf("%w: unknown CSV column %q", ErrInvalidInput, header[i])
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: CSV column %q is specified more than once", ErrInvalidInput, column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+	for _, column := range []string{"name", "surname"} {
+		if !seen[column] {
+			return nil, fmt.Errorf("%w: CSV column %q is required", ErrInvalidInput, column)
+		}
+	}
+
+	return &csvReader{r: reader, columns: header}, nil
+}
+
+func (c *csvReader) next() (row, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return row{}, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return row{line: parseErr.StartLine, err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return row{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	if len(record) != len(c.columns) {
+		return row{line: line, err: fmt.Errorf("expected %d fields, got %d", len(c.columns), len(record))}, nil
+	}
+
+	r := row{line: line}
+	for i, column := range c.columns {
+		if err := csvColumns[column](&r, record[i]); err != nil {
+			return row{line: line, err: err}, nil
+		}
+	}
+	return r, nil
+}
+
+// ndjsonReader читает по одному JSON-объекту Person в строке; пустые строки пропускаются.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonReader) next() (row, error) {
+	for n.scanner.Scan() {
+		n.line++
+		data := bytes.TrimSpace(n.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		// Возраст читается в указатель, чтобы отличить переданный 0 от отсутствующего
+		var record struct {
+			types.Person
+			Age *int `json:"age"`
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			return row{line: n.line, err: fmt.Errorf("invalid JSON: %v", err)}, nil
+		}
+		if decoder.More() {
+			return row{line: n.line, err: fmt.Errorf("invalid JSON: line must contain a single object")}, nil
+		}
+		r := row{line: n.line, person: record.Person}
+		if record.Age != nil {
+			r.person.Age, r.ageSet = *record.Age, true
+		}
+		return r, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return row{}, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidInput, n.line+1, maxLineSize)
+		}
+		return row{}, err
+	}
+	return row{}, io.EOF
+}
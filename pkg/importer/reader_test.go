@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAll читает все строки и возвращает их вместе с ошибкой чтения.
+func readAll(t *testing.T, input, format string) ([]row, error) {
+	t.Helper()
+	reader, err := newRowReader(strings.NewReader(input), format)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	for {
+		r, err := reader.next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, r)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "\ufeffName,Surname,Age\n" +
+		"Oleg,Samsonov,29\n" +
+		"\n" +
+		"Katerina,Samsonova,abc\n" +
+		"Vasiliy,Juk\n" +
+		"\"Iliya\",\"Pash\nkovskiy\",30\n" +
+		"Vladimir,Chzen,\n"
+
+	rows, err := readAll(t, input, FormatCSV)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("Expected 5 rows, got %d", len(rows))
+	}
+
+	if rows[0].line != 2 || rows[0].err != nil || rows[0].person.Name != "Oleg" || rows[0].person.Age != 29 || !rows[0].ageSet {
+		t.Fatalf("Unexpected first row: %+v", rows[0])
+	}
+	if rows[1].line != 4 || rows[1].err == nil {
+		t.Fatalf("Expected invalid age on line 4: %+v", rows[1])
+	}
+	if rows[2].line != 5 || rows[2].err == nil {
+		t.Fatalf("Expected missing field on line 5: %+v", rows[2])
+	}
+	// Многострочное поле: номер строки - начало записи
+	if rows[3].line != 6 || rows[3].person.Surname != "Pash\nkovskiy" {
+		t.Fatalf("Unexpected multiline row: %+v", rows[3])
+	}
+	if rows[4].line != 8 || rows[4].err != nil || rows[4].person.Age != 0 || rows[4].ageSet {
+		t.Fatalf("Unexpected last row: %+v", rows[4])
+	}
+}
+
+func TestCSVReaderHeader(t *testing.T) {
+	for _, input := range []string{"", "name\n", "name,surname,nickname\n", "name,surname,Name\n"} {
+		if _, err := readAll(t, input, FormatCSV); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("Expected invalid input for header %q, got %v", input, err)
+		}
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"name": "Oleg", "surname": "Samsonov", "age": 29}` + "\n" +
+		"\n" +
+		`{"name": "Katerina", "surname": "Samsonova", "nickname": "Katya"}` + "\n" +
+		`{"name": "Vasiliy"` + "\n" +
+		`{"name": "Iliya"} {"name": "Vladimir"}` + "\n" +
+		`{"name": "Ivan", "surname": "Ivanov", "age": 0}` + "\n" +
+		`{"name": "Petr", "surname": "Petrov"}` + "\n"
+
+	rows, err := readAll(t, input, FormatNDJSON)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(rows) != 6 {
+		t.Fatalf("Expected 6 rows, got %d", len(rows))
+	}
+
+	if rows[0].line != 1 || rows[0].err != nil || rows[0].person.Age != 29 || !rows[0].ageSet {
+		t.Fatalf("Unexpected first row: %+v", rows[0])
+	}
+	for i, line := range []int{3, 4, 5} {
+		if rows[i+1].line != line || rows[i+1].err == nil {
+			t.Fatalf("Expected error on line %d: %+v", line, rows[i+1])
+		}
+	}
+	// Возраст 0 передан явно, а не отсутствует
+	if rows[4].err != nil || rows[4].person.Age != 0 || !rows[4].ageSet || rows[5].ageSet {
+		t.Fatalf("Unexpected age presence: %+v, %+v", rows[4], rows[5])
+	}
+
+	if _, err := readAll(t, strings.Repeat("x", maxLineSize+1), FormatNDJSON); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Expected invalid input for a too long line, got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if FormatFromName("people.CSV") != FormatCSV || FormatFromName("people.jsonl") != FormatNDJSON || FormatFromName("people.xlsx") != "" {
+		t.Fatal("Unexpected format by file name")
+	}
+	if FormatFromContentType("text/csv; charset=utf-8") != FormatCSV || FormatFromContentType("application/x-ndjson") != FormatNDJSON {
+		t.Fatal("Unexpected format by content type")
+	}
+}
@@ -0,0 +1,295 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MergePatch применяет JSON Merge Patch (RFC 7396) к документу doc.
+// Значение null в патче удаляет поле, вложенные объекты объединяются рекурсивно.
+// Исходный документ не изменяется.
+func MergePatch(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+
+	object, ok := p.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("merge patch must be a JSON object")
+	}
+
+	return mergeObject(deepCopy(doc).(map[string]interface{}), object), nil
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			targetObject, ok := target[key].(map[string]interface{})
+			if !ok {
+				targetObject = map[string]interface{}{}
+			}
+			target[key] = mergeObject(targetObject, patchObject)
+			continue
+		}
+
+		target[key] = value
+	}
+	return target
+}
+
+// Operation - операция JSON Patch (RFC 6902).
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply применяет JSON Patch (RFC 6902) к документу doc. Операции выполняются
+// последовательно; при ошибке любой из них патч не применяется целиком.
+// Исходный документ не изменяется.
+func Apply(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("JSON patch must be an array of operations: %w", err)
+	}
+
+	var result interface{} = deepCopy(doc)
+	for i, op := range ops {
+		var err error
+		result, err = applyOperation(result, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	object, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("patched document must be a JSON object")
+	}
+	return object, nil
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, op.Path, value)
+		case "replace":
+			if _, err := get(doc, op.Path); err != nil {
+				return nil, err
+			}
+			if op.Path == "" {
+				return value, nil
+			}
+			return replaceAt(doc, op.Path, value)
+		default:
+			current, err := get(doc, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		return remove(doc, op.Path)
+
+	case "move", "copy":
+		value, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			if doc, err = remove(doc, op.From); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return add(doc, op.Path, value)
+	}
+
+	return nil, fmt.Errorf("unknown operation")
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901) на токены.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return current, nil
+}
+
+// add вставляет value по указателю и возвращает обновленный документ.
+func add(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := get(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index := len(node)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		if parentPointer == "" {
+			return node, nil
+		}
+		return replaceAt(doc, parentPointer, node)
+	}
+
+	return nil, fmt.Errorf("path %q does not exist", pointer)
+}
+
+// remove удаляет значение по указателю и возвращает обновленный документ.
+func remove(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := get(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index:index], node[index+1:]...)
+		if parentPointer == "" {
+			return node, nil
+		}
+		return replaceAt(doc, parentPointer, node)
+	}
+
+	return nil, fmt.Errorf("path %q does not exist", pointer)
+}
+
+// replaceAt заменяет существующее значение по указателю.
+func replaceAt(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	doc, err := remove(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return add(doc, pointer, value)
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
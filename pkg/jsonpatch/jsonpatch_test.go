@@ -0,0 +1,87 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func document() map[string]interface{} {
+	return map[string]interface{}{
+		"name":       "Dmitriy",
+		"patronymic": "Vasilevich",
+		"age":        42.0,
+		"tags":       []interface{}{"a", "b"},
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := document()
+
+	result, err := MergePatch(doc, []byte(`{"patronymic": null, "age": 0, "gender": "male"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"name":   "Dmitriy",
+		"age":    0.0,
+		"gender": "male",
+		"tags":   []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+
+	if doc["patronymic"] != "Vasilevich" {
+		t.Fatal("Source document must not be modified")
+	}
+
+	if _, err := MergePatch(doc, []byte(`["not", "an", "object"]`)); err == nil {
+		t.Fatal("Expected error for non-object merge patch")
+	}
+}
+
+func TestApply(t *testing.T) {
+	patch := `[
+		{"op": "test", "path": "/name", "value": "Dmitriy"},
+		{"op": "remove", "path": "/patronymic"},
+		{"op": "replace", "path": "/age", "value": 0},
+		{"op": "add", "path": "/tags/1", "value": "x"},
+		{"op": "add", "path": "/tags/-", "value": "z"},
+		{"op": "copy", "from": "/name", "path": "/nickname"},
+		{"op": "move", "from": "/nickname", "path": "/alias"}
+	]`
+
+	result, err := Apply(document(), []byte(patch))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"name":  "Dmitriy",
+		"age":   0.0,
+		"tags":  []interface{}{"a", "x", "b", "z"},
+		"alias": "Dmitriy",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	patches := []string{
+		`[{"op": "test", "path": "/name", "value": "Oleg"}]`,
+		`[{"op": "remove", "path": "/missing"}]`,
+		`[{"op": "replace", "path": "/missing", "value": 1}]`,
+		`[{"op": "add", "path": "/tags/5", "value": 1}]`,
+		`[{"op": "unknown", "path": "/name"}]`,
+		`[{"op": "add", "path": "name", "value": 1}]`,
+		`{"op": "add"}`,
+	}
+
+	for _, patch := range patches {
+		if _, err := Apply(document(), []byte(patch)); err == nil {
+			t.Fatalf("Expected error for patch %s", patch)
+		}
+	}
+}
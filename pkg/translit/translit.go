@@ -0,0 +1,70 @@
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// cyrillic - упрощенная транслитерация кириллицы в латиницу.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "ch", 'ш': "sh", 'щ': "sh", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "i", 'є': "e", 'ґ': "g",
+}
+
+// variants сводит разные латинские варианты записи одного звука к одному.
+// Порядок важен: многобуквенные сочетания заменяются раньше одиночных букв.
+var variants = strings.NewReplacer(
+	"shch", "sh", "sch", "sh",
+	"kh", "h",
+	"ts", "c", "tz", "c",
+	"ph", "f",
+	"ya", "ia", "ja", "ia",
+	"yu", "iu", "ju", "iu",
+	"ye", "e", "je", "e",
+	"yo", "e", "jo", "e",
+	"w", "v",
+	"q", "k",
+	"x", "ks",
+	"y", "i",
+	"j", "i",
+)
+
+// Normalize приводит имя, записанное кириллицей или латиницей, к единому
+// латинскому виду, чтобы разные варианты транслитерации совпадали:
+// "Дмитрий", "Dmitriy" и "Dmitry" дают "dmitri".
+func Normalize(s string) string {
+	var latin strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if repl, ok := cyrillic[r]; ok {
+			latin.WriteString(repl)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			latin.WriteRune(r)
+		} else {
+			latin.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(variants.Replace(latin.String()))
+	for i, word := range words {
+		words[i] = squeeze(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// squeeze убирает повторяющиеся подряд буквы ("vassilii" -> "vasili").
+func squeeze(word string) string {
+	var b strings.Builder
+	var prev rune
+	for i, r := range word {
+		if i > 0 && r == prev {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package translit
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	groups := [][]string{
+		{"Дмитрий", "Dmitriy", "Dmitry", "Dmitrij", "DMITRII"},
+		{"Юлия", "Julia", "Yulia", "Yuliya"},
+		{"Сергей", "Sergey", "Sergei"},
+		{"Василий", "Vasiliy", "Vassily"},
+		{"Хрущёв", "Khrushchev", "Hrushev"},
+		{"Елена", "Yelena", "Elena"},
+	}
+
+	for _, group := range groups {
+		expected := Normalize(group[0])
+		for _, variant := range group[1:] {
+			if got := Normalize(variant); got != expected {
+				t.Fatalf("Normalize(%q) = %q, expected %q (as for %q)", variant, got, expected, group[0])
+			}
+		}
+	}
+
+	if got := Normalize("  Ушаков-Дмитрий  Васильевич "); got != "ushakov dmitri vasilevich" {
+		t.Fatalf("Unexpected normalization of full name: %q", got)
+	}
+}
@@ -1,38 +1,305 @@
 package types
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Модель для таблицы "people".
 type Person struct {
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	Patronymic  string `json:"patronymic"`
-	Age         int    `json:"age"`
-	Gender      string `json:"gender"`
-	Nationality string `json:"nationality"`
+	ID                int               `json:"id"`
+	Name              string            `json:"name"`
+	Surname           string            `json:"surname"`
+	Patronymic        string            `json:"patronymic"`
+	Age               int               `json:"age"`
+	AgeCount          int               `json:"age_count"`
+	Gender            string            `json:"gender"`
+	GenderProbability float64           `json:"gender_probability"`
+	Nationality       string            `json:"nationality"`
+	Nationalities     []Nationality     `json:"nationalities,omitempty"`
+	EnrichmentStatus  string            `json:"enrichment_status"`
+	EnrichmentFields  map[string]string `json:"enrichment_fields,omitempty"` // статус обогащения каждого поля из EnrichedFields
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	Version           int               `json:"version"`              // увеличивается при каждом изменении, передается в ETag
+	DeletedAt         *time.Time        `json:"deleted_at,omitempty"` // время удаления, пока запись не удалена окончательно
+	Score             *float64          `json:"score,omitempty"`      // сходство с запросом при нечетком поиске
+}
+
+// Статусы обогащения записи и отдельных полей.
+// Запись имеет статус partial, если часть полей обогащена, а остальные - нет.
+const (
+	EnrichmentPending  = "pending"
+	EnrichmentComplete = "complete"
+	EnrichmentPartial  = "partial"
+	EnrichmentFailed   = "failed"
+)
+
+// Обогащаемые поля записи: каждое заполняется своим источником.
+const (
+	FieldAge         = "age"
+	FieldGender      = "gender"
+	FieldNationality = "nationality"
+)
+
+// EnrichedFields - все обогащаемые поля.
+var EnrichedFields = []string{FieldAge, FieldGender, FieldNationality}
+
+// FieldEnriched проверяет, что поле field уже обогащено.
+func (p *Person) FieldEnriched(field string) bool {
+	return p.EnrichmentFields[field] == EnrichmentComplete
+}
+
+// EnrichedCount возвращает количество уже обогащенных полей.
+func (p *Person) EnrichedCount() int {
+	count := 0
+	for _, field := range EnrichedFields {
+		if p.FieldEnriched(field) {
+			count++
+		}
+	}
+	return count
+}
+
+// SetFieldStatus задает статус обогащения поля field.
+func (p *Person) SetFieldStatus(field, status string) {
+	if p.EnrichmentFields == nil {
+		p.EnrichmentFields = map[string]string{}
+	}
+	p.EnrichmentFields[field] = status
+}
+
+// MarkUnenriched задает статус status всем еще не обогащенным полям.
+func (p *Person) MarkUnenriched(status string) {
+	for _, field := range EnrichedFields {
+		if !p.FieldEnriched(field) {
+			p.SetFieldStatus(field, status)
+		}
+	}
+}
+
+// UpdateEnrichmentStatus заполняет статусы полей и вычисляет по ним статус записи.
+// Если статусы полей не заданы, все поля получают статус записи (по умолчанию complete),
+// иначе поля без статуса считаются ожидающими обогащения.
+func (p *Person) UpdateEnrichmentStatus() {
+	if p.EnrichmentFields == nil {
+		status := p.EnrichmentStatus
+		if status == "" {
+			status = EnrichmentComplete
+		}
+		p.MarkUnenriched(status)
+	}
+
+	complete, failed := 0, 0
+	for _, field := range EnrichedFields {
+		switch p.EnrichmentFields[field] {
+		case EnrichmentComplete:
+			complete++
+		case EnrichmentFailed:
+			failed++
+		default:
+			p.EnrichmentFields[field] = EnrichmentPending
+		}
+	}
+
+	switch {
+	case complete+failed < len(EnrichedFields):
+		p.EnrichmentStatus = EnrichmentPending
+	case failed == 0:
+		p.EnrichmentStatus = EnrichmentComplete
+	case complete > 0:
+		p.EnrichmentStatus = EnrichmentPartial
+	default:
+		p.EnrichmentStatus = EnrichmentFailed
+	}
+}
+
+// Запись истории изменений человека из таблицы "people_history".
+// Before и After - снимки записи до и после изменения (null при создании и окончательном удалении).
+type PersonHistory struct {
+	ID        int64           `json:"id"`
+	PersonID  int             `json:"person_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Действия, сохраняемые в истории изменений.
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryEnrich  = "enrich"
+	HistoryPurge   = "purge" // окончательное удаление; After - null
+)
+
+// Страница истории изменений человека.
+type PersonHistoryPage struct {
+	Items      []*PersonHistory `json:"items"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
+	Total      int              `json:"total"`
+	TotalPages int              `json:"total_pages"`
+	Next       *string          `json:"next"`
+	Prev       *string          `json:"prev"`
+}
+
+// Задание на обогащение из очереди "enrichment_jobs".
+type EnrichmentJob struct {
+	ID       int64
+	PersonID int
+	Attempts int
 }
 
 // Условия фильтрации записей.
+// Поля-указатели не заданы, если равны nil, поэтому ноль - допустимое значение.
 type PersonFilter struct {
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	Patronymic  string `json:"patronymic"`
-	MinAge      int    `json:"minage"`
-	MaxAge      int    `json:"maxage"`
-	Gender      string `json:"gender"`
-	Nationality string `json:"nationality"`
-	Page        int    `json:"page"`
-	PageSize    int    `json:"pagesize"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Patronymic  string     `json:"patronymic"`
+	MinAge      *int       `json:"minage"`
+	MaxAge      *int       `json:"maxage"`
+	Gender      StringList `json:"gender"`      // любое из значений
+	Nationality StringList `json:"nationality"` // любое из значений
+	Page        int        `json:"page"`
+	PageSize    int        `json:"pagesize"`
+
+	// Отрицание: ни одно из значений (записи без значения поля тоже подходят).
+	NotGender      StringList `json:"not_gender"`
+	NotNationality StringList `json:"not_nationality"`
+
+	// Поля, которые должны быть пустыми (IsNull) или заполненными (NotNull):
+	// patronymic, age, gender, nationality. Например, IsNull: ["age"] - еще не обогащенные записи.
+	IsNull  StringList `json:"null"`
+	NotNull StringList `json:"notnull"`
+
+	// Match - режим сравнения Name/Surname/Patronymic: exact (по умолчанию), prefix или contains.
+	Match string `json:"match"`
+	// Q - поиск сразу по имени, фамилии и отчеству.
+	Q string `json:"q"`
+	// Search - режим поиска по Q: fulltext (по умолчанию) или fuzzy - нечеткий,
+	// с учетом транслитерации (кириллица и латиница).
+	Search string `json:"search"`
+
+	// Sort - поля сортировки через запятую, "-" перед полем - по убыванию ("-age,surname").
+	Sort string `json:"sort"`
+
+	// IncludeDeleted добавляет в список удаленные записи (только для администратора).
+	IncludeDeleted bool `json:"include_deleted"`
+
+	// Cursor включает keyset-пагинацию: пустая строка - первая страница,
+	// иначе - курсор из поля next_cursor предыдущего ответа.
+	Cursor *string `json:"cursor"`
+	// After - позиция, декодированная из курсора (заполняется обработчиком).
+	After *CursorPosition `json:"-"`
+}
+
+// Список строк, который в JSON можно передать как одну строку или как массив.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = nil
+		if single != "" {
+			*l = StringList{single}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Позиция в списке людей для keyset-пагинации.
+type CursorPosition struct {
+	Keys   []interface{} `json:"k,omitempty"` // значения ключей сортировки последней записи
+	ID     int           `json:"id"`          // id последней записи
+	Filter string        `json:"f"`           // отпечаток фильтра, для которого выдан курсор
+}
+
+// Страница списка людей с данными для навигации.
+type PersonPage struct {
+	Items      []*Person `json:"items"`
+	Page       int       `json:"page"`
+	PageSize   int       `json:"page_size"`
+	Total      int       `json:"total"`
+	TotalPages int       `json:"total_pages"`
+	Next       *string   `json:"next"`
+	Prev       *string   `json:"prev"`
+}
+
+// Страница списка людей при keyset-пагинации.
+type PersonCursorPage struct {
+	Items      []*Person `json:"items"`
+	PageSize   int       `json:"page_size"`
+	NextCursor *string   `json:"next_cursor"`
+	Next       *string   `json:"next"`
+}
+
+// Результат создания одного человека из пакетного запроса.
+type BatchItemResult struct {
+	Index  int     `json:"index"`  // позиция в запросе
+	Status int     `json:"status"` // HTTP-статус, соответствующий результату
+	Person *Person `json:"person,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Ответ на пакетное создание людей.
+type BatchResult struct {
+	Items   []BatchItemResult `json:"items"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+}
+
+// Отчет об импорте людей из файла.
+type ImportReport struct {
+	Total    int               `json:"total"`    // прочитано строк с данными
+	Imported int               `json:"imported"` // сохранено записей
+	Rejected []ImportRejection `json:"rejected"` // отклоненные строки по возрастанию номера
+}
+
+// Отклоненная при импорте строка.
+type ImportRejection struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Reject добавляет в отчет отклоненную строку.
+func (r *ImportReport) Reject(line int, err error) {
+	r.Rejected = append(r.Rejected, ImportRejection{Line: line, Error: err.Error()})
+}
+
+// Квота запросов источника обогащения. Пока источник не ответил ни разу
+// или после восстановления квоты Limit, Remaining и Reset неизвестны (null).
+type ProviderQuota struct {
+	Provider  string     `json:"provider"`
+	Limit     *int       `json:"limit"`     // запросов в сутки
+	Remaining *int       `json:"remaining"` // осталось запросов
+	Reset     *time.Time `json:"reset"`     // время восстановления квоты
+	Exhausted bool       `json:"exhausted"` // запросы не отправляются до Reset
 }
 
 // Ответ от api ожидаемый возраст
 type AgifyResponse struct {
-	Count int `json:"count"`
-	Age   int `json:"age"`
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+	Age   int    `json:"age"`
 }
 
 // Ответ от api ожидаемый пол
 type GenderizeResponse struct {
-	Count  int    `json:"count"`
-	Gender string `json:"gender"`
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
 }
 
 // Ответ от api ожидаемая национальность
@@ -42,6 +309,7 @@ type NationalizeResponse struct {
 	Country []Nationality `json:"country"`
 }
 
+// Вероятность принадлежности к стране (список упорядочен по убыванию вероятности).
 type Nationality struct {
 	CountryID   string  `json:"country_id"`
 	Probability float64 `json:"probability"`
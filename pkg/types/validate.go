@@ -0,0 +1,72 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidatePerson убирает пробелы по краям ФИО, сбрасывает статус обогащения и проверяет обязательные поля
+// и их длину (ограничения таблицы people).
+func ValidatePerson(person *Person) error {
+	person.Name = strings.TrimSpace(person.Name)
+	person.Surname = strings.TrimSpace(person.Surname)
+	person.Patronymic = strings.TrimSpace(person.Patronymic)
+	// Статус обогащения определяет сервер, а не клиент
+	person.EnrichmentStatus, person.EnrichmentFields = "", nil
+
+	if person.Name == "" {
+		return fmt.Errorf("Field 'name' is required")
+	}
+	if person.Surname == "" {
+		return fmt.Errorf("Field 'surname' is required")
+	}
+
+	fields := []struct{ name, value string }{
+		{"name", person.Name},
+		{"surname", person.Surname},
+		{"patronymic", person.Patronymic},
+	}
+	for _, field := range fields {
+		if utf8.RuneCountInString(field.value) > 50 {
+			return fmt.Errorf("Field '%s' must be at most 50 characters", field.name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateEnrichment проверяет поля, которые обычно заполняет обогащение,
+// если они переданы вместе с записью (ограничения таблицы people). Переданные поля
+// (возраст при ageSet, в том числе 0, непустые пол и национальность) получают статус complete,
+// остальные - failed, чтобы их можно было обогатить позже; статус записи вычисляется по ним.
+func ValidateEnrichment(person *Person, ageSet bool) error {
+	person.Gender = strings.TrimSpace(person.Gender)
+	person.Nationality = strings.TrimSpace(person.Nationality)
+
+	if person.Age < 0 {
+		return fmt.Errorf("Field 'age' must be a non-negative integer")
+	}
+	if utf8.RuneCountInString(person.Gender) > 10 {
+		return fmt.Errorf("Field 'gender' must be at most 10 characters")
+	}
+	if utf8.RuneCountInString(person.Nationality) > 255 {
+		return fmt.Errorf("Field 'nationality' must be at most 255 characters")
+	}
+
+	supplied := map[string]bool{
+		FieldAge:         ageSet,
+		FieldGender:      person.Gender != "",
+		FieldNationality: person.Nationality != "",
+	}
+	for _, field := range EnrichedFields {
+		status := EnrichmentFailed
+		if supplied[field] {
+			status = EnrichmentComplete
+		}
+		person.SetFieldStatus(field, status)
+	}
+	person.UpdateEnrichmentStatus()
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	db "junior-test/db/models"
+	"junior-test/pkg/enrich"
+	"junior-test/pkg/types"
+	"log"
+	"sync"
+	"time"
+)
+
+// EnrichmentPool обрабатывает очередь заданий на обогащение несколькими воркерами.
+type EnrichmentPool struct {
+	Repository *db.SQLPersonRepository
+	Enrichers  []enrich.Enricher
+
+	Workers      int           // количество параллельных воркеров
+	MaxAttempts  int           // после стольких неудачных попыток необогащенные поля помечаются как failed
+	PollInterval time.Duration // пауза между опросами пустой очереди
+	Lease        time.Duration // время, на которое воркер блокирует задание
+	RetryDelay   time.Duration // базовая задержка повтора, удваивается с каждой попыткой
+}
+
+// Run запускает воркеры и блокируется до отмены ctx.
+func (p *EnrichmentPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			p.work(ctx, n)
+		}(i + 1)
+	}
+
+	log.Printf("Enrichment pool started with %d workers", p.Workers)
+	wg.Wait()
+	log.Println("Enrichment pool stopped")
+}
+
+func (p *EnrichmentPool) work(ctx context.Context, n int) {
+	for ctx.Err() == nil {
+		job, err := p.Repository.ClaimEnrichmentJob(p.Lease)
+		if err != nil {
+			log.Printf("Worker %d: failed to claim job: %v", n, err)
+		}
+
+		if job != nil {
+			p.process(ctx, job)
+			continue
+		}
+
+		// Очередь пуста или недоступна: ждем перед следующим опросом
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.PollInterval):
+		}
+	}
+}
+
+// process обрабатывает задание. Если квота одного из источников исчерпана, откладывается
+// только это задание - до восстановления квоты; задания, которым нужны другие
+// источники, воркеры продолжают выполнять.
+func (p *EnrichmentPool) process(ctx context.Context, job *types.EnrichmentJob) {
+	// Удаленную запись тоже обогащаем: ее могут восстановить до окончательного удаления
+	person, err := p.Repository.GetPersonIncludingDeleted(job.PersonID)
+	if err != nil {
+		p.retry(job, err)
+		return
+	}
+	if person == nil {
+		// Запись удалена окончательно, обогащать нечего
+		p.Repository.DeleteEnrichmentJob(job)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, p.Lease)
+	defer cancel()
+
+	enriched := person.EnrichedCount()
+	if err := enrich.Run(jobCtx, person, p.Enrichers); err != nil {
+		if person.EnrichedCount() > enriched {
+			// Сохраняем поля, обогащенные в этой попытке: следующая обогатит только оставшиеся.
+			// Если запись изменилась, прогресс не сохраняется и поля обогатятся заново
+			p.Repository.SaveEnrichmentProgress(job, person)
+		}
+
+		var quotaErr *enrich.QuotaError
+		if errors.As(err, &quotaErr) {
+			p.Repository.PostponeEnrichmentJob(job, err, quotaErr.Reset)
+			return
+		}
+		p.retry(job, err)
+		return
+	}
+
+	err = p.Repository.CompleteEnrichmentJob(job, person)
+	if errors.Is(err, db.ErrVersionMismatch) {
+		// Запись изменили во время обогащения: задание сразу выполнится заново
+		// по свежим данным, попытка не засчитывается
+		p.Repository.PostponeEnrichmentJob(job, err, time.Now())
+		return
+	}
+	if err != nil {
+		p.retry(job, err)
+	}
+}
+
+// retry откладывает задание с экспоненциальной задержкой, а если попытки исчерпаны,
+// помечает необогащенные поля как failed.
+func (p *EnrichmentPool) retry(job *types.EnrichmentJob, err error) {
+	if job.Attempts >= p.MaxAttempts {
+		p.Repository.FailEnrichmentJob(job, err)
+		return
+	}
+
+	delay := p.RetryDelay << uint(job.Attempts-1)
+	log.Printf("Enrichment job %d attempt %d failed: %v", job.ID, job.Attempts, err)
+	p.Repository.RetryEnrichmentJob(job, err, time.Now().Add(delay))
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	db "junior-test/db/models"
+	"log"
+	"time"
+)
+
+// Purger периодически окончательно удаляет записи, срок хранения которых после удаления истек,
+// и устаревшие ответы из кэша обогащения.
+type Purger struct {
+	Repository *db.SQLPersonRepository
+
+	Retention time.Duration // сколько хранится удаленная запись
+	Interval  time.Duration // период запуска очистки
+
+	Cache    *db.SQLEnrichmentCache // nil - кэш в БД не используется
+	CacheTTL time.Duration          // срок жизни ответа в кэше
+}
+
+// Run выполняет очистку сразу и затем каждые Interval до отмены ctx.
+func (p *Purger) Run(ctx context.Context) {
+	log.Printf("Purger started, deleted people are kept for %s", p.Retention)
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Repository.PurgeDeletedPeople(p.Retention); err != nil {
+			log.Printf("Purger: failed to purge deleted people: %v", err)
+		}
+		if p.Cache != nil {
+			if _, err := p.Cache.DeleteExpiredEnrichments(time.Now().Add(-p.CacheTTL)); err != nil {
+				log.Printf("Purger: failed to delete expired enrichment cache: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}